@@ -4,13 +4,17 @@ import (
 	"log"
 	"os"
 
+	"github.com/navigator-systems/jrx/internal/version"
 	"github.com/urfave/cli/v2"
 )
 
 func InitCli() {
 	app := &cli.App{
-		Name:  "jrx",
-		Usage: "Just a simple project management CLI",
+		Name:    "jrx",
+		Usage:   "Just a simple project management CLI",
+		Version: version.Version,
+		// Repeated flags such as --var carry values that may contain commas
+		DisableSliceFlagSeparator: true,
 		Commands: []*cli.Command{
 			projectCmd,
 			templatesCmd,
@@ -7,7 +7,18 @@ import (
 var (
 	gitOrg          string
 	varsFlag        string
+	varsFileFlag    string
 	gitHubOrg       string
+	gitLabGroup     string
+	providerName    string
+	namespace       string
+	forceFlag       bool
+	statFlag        bool
+	noInputFlag     bool
+	allowHooksFlag  bool
+	noHooksFlag     bool
+	dryRunFlag      bool
+	showFlag        string
 	templateVersion string
 )
 
@@ -17,6 +28,8 @@ var projectCmd = &cli.Command{
 	Usage:   "Manage projects",
 	Subcommands: []*cli.Command{
 		newCmd,
+		upgradeCmd,
+		diffCmd,
 	},
 }
 
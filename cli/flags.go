@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"github.com/navigator-systems/jrx/internal/config"
 	"github.com/urfave/cli/v2"
 )
 
@@ -11,6 +12,17 @@ var flagVars = &cli.StringFlag{
 	Destination: &varsFlag,
 }
 
+var flagVar = &cli.StringSliceFlag{
+	Name:  "var",
+	Usage: "Template variable as key=value, may be repeated; the value can contain commas and equal signs",
+}
+
+var flagVarsFile = &cli.StringFlag{
+	Name:        "vars-file",
+	Usage:       "TOML, YAML or JSON file with template variable values",
+	Destination: &varsFileFlag,
+}
+
 var flagPort = &cli.StringFlag{
 	Name:    "port",
 	Aliases: []string{"p"},
@@ -25,9 +37,85 @@ var flagGitHubOrg = &cli.StringFlag{
 	Destination: &gitHubOrg,
 }
 
+var flagGitLabGroup = &cli.StringFlag{
+	Name:        "gitlab-group",
+	Usage:       "GitLab group or subgroup path (e.g., platform/services) to create the project in",
+	Destination: &gitLabGroup,
+}
+
+var flagProvider = &cli.StringFlag{
+	Name:        "provider",
+	Usage:       "Name of the SCM provider from .jrxrc [providers] to create the repository on",
+	Destination: &providerName,
+}
+
+var flagNamespace = &cli.StringFlag{
+	Name:        "namespace",
+	Usage:       "Organization or group of the provider to create the repository in",
+	Destination: &namespace,
+}
+
 var templateVersionFlag = &cli.StringFlag{
 	Name:        "template-version",
 	Aliases:     []string{"t"},
 	Usage:       "Version of the template to use (e.g., main, v1.2.3). If not specified, uses the default version from config.",
 	Destination: &templateVersion,
 }
+
+var flagForce = &cli.BoolFlag{
+	Name:        "force",
+	Aliases:     []string{"f"},
+	Usage:       "Upgrade even if the project has uncommitted changes",
+	Destination: &forceFlag,
+}
+
+var flagStat = &cli.BoolFlag{
+	Name:        "stat",
+	Usage:       "Only print a summary of changed lines per file",
+	Destination: &statFlag,
+}
+
+var flagPath = &cli.StringSliceFlag{
+	Name:  "path",
+	Usage: "Only compare files matching this glob or directory (repeatable)",
+}
+
+var flagNoInput = &cli.BoolFlag{
+	Name:        "no-input",
+	Usage:       "Do not prompt for template variables, fail if a required value is missing",
+	Destination: &noInputFlag,
+}
+
+var flagAllowHooks = &cli.BoolFlag{
+	Name:        "allow-hooks",
+	Usage:       "Run the commands declared by template hooks without asking",
+	Destination: &allowHooksFlag,
+}
+
+var flagNoHooks = &cli.BoolFlag{
+	Name:        "no-hooks",
+	Usage:       "Do not run the commands declared by template hooks",
+	Destination: &noHooksFlag,
+}
+
+var flagDryRun = &cli.BoolFlag{
+	Name:        "dry-run",
+	Usage:       "Render the template and list what would be created, without writing anything",
+	Destination: &dryRunFlag,
+}
+
+var flagShow = &cli.StringFlag{
+	Name:        "show",
+	Usage:       "With --dry-run, print the rendered content of this file",
+	Destination: &showFlag,
+}
+
+var flagTemplatesDir = &cli.StringFlag{
+	Name:    "templates-dir",
+	Usage:   "Read templates from this local directory instead of the downloaded templates, to try changes without pushing them",
+	EnvVars: []string{"JRX_TEMPLATES_DIR"},
+	Action: func(c *cli.Context, dir string) error {
+		config.SetTemplatesDir(dir)
+		return nil
+	},
+}
@@ -17,14 +17,70 @@ var newCmd = &cli.Command{
 		name := c.Args().Get(0)
 		template := c.Args().Get(1)
 
-		cmd.NewCmd(name, template, varsFlag, gitHubOrg, templateVersion)
+		cmd.NewCmd(name, template, cmd.NewOptions{
+			Vars:        varsFlag,
+			VarFlags:    c.StringSlice("var"),
+			VarsFile:    varsFileFlag,
+			GithubOrg:   gitHubOrg,
+			GitlabGroup: gitLabGroup,
+			Provider:    providerName,
+			Namespace:   namespace,
+			Version:     templateVersion,
+			NoInput:     noInputFlag,
+			AllowHooks:  allowHooksFlag,
+			NoHooks:     noHooksFlag,
+			DryRun:      dryRunFlag,
+			Show:        showFlag,
+		})
 
 		return nil
 	},
 	Flags: []cli.Flag{
 		flagVars,
+		flagVar,
+		flagVarsFile,
 		flagGitHubOrg,
+		flagGitLabGroup,
+		flagProvider,
+		flagNamespace,
 		templateVersionFlag,
+		flagNoInput,
+		flagAllowHooks,
+		flagNoHooks,
+		flagDryRun,
+		flagShow,
+		flagTemplatesDir,
+	},
+}
+
+var upgradeCmd = &cli.Command{
+	Name:      "upgrade",
+	Usage:     "Re-apply a newer template version to a generated project (three-way merge)",
+	ArgsUsage: "[project_dir]",
+	Action: func(c *cli.Context) error {
+		cmd.UpgradeCmd(c.Args().Get(0), templateVersion, forceFlag)
+		return nil
+	},
+	Flags: []cli.Flag{
+		templateVersionFlag,
+		flagForce,
+		flagTemplatesDir,
+	},
+}
+
+var diffCmd = &cli.Command{
+	Name:      "diff",
+	Usage:     "Show how a generated project differs from the template it came from",
+	ArgsUsage: "[project_dir]",
+	Action: func(c *cli.Context) error {
+		cmd.DiffCmd(c.Args().Get(0), templateVersion, c.StringSlice("path"), statFlag)
+		return nil
+	},
+	Flags: []cli.Flag{
+		templateVersionFlag,
+		flagStat,
+		flagPath,
+		flagTemplatesDir,
 	},
 }
 
@@ -32,13 +88,15 @@ var newCmd = &cli.Command{
 var tmplInfoCmd = &cli.Command{
 	Name: "list",
 
-	Usage: "Get information about templates",
+	Usage:     "Get information about templates",
+	ArgsUsage: "[source]",
 	Action: func(c *cli.Context) error {
-		cmd.TmplInfoCmd(templateVersion)
+		cmd.TmplInfoCmd(c.Args().Get(0), templateVersion)
 		return nil
 	},
 	Flags: []cli.Flag{
 		templateVersionFlag,
+		flagTemplatesDir,
 	},
 }
 
@@ -64,5 +122,6 @@ var serverCmd = &cli.Command{
 	},
 	Flags: []cli.Flag{
 		flagPort,
+		flagTemplatesDir,
 	},
 }
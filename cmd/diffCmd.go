@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/navigator-systems/jrx/internal/config"
+	"github.com/navigator-systems/jrx/internal/diff"
+	"github.com/navigator-systems/jrx/internal/generator"
+)
+
+// matchPaths reports whether path matches one of the filters (a glob or a directory prefix)
+func matchPaths(path string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		filter = filepath.Clean(filter)
+		if matched, _ := filepath.Match(filter, path); matched {
+			return true
+		}
+		if path == filter || strings.HasPrefix(path, filter+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func DiffCmd(projectDir, version string, paths []string, stat bool) {
+	if projectDir == "" {
+		projectDir = "."
+	}
+
+	// Read the provenance recorded at generation time
+	state, err := generator.ReadProjectState(projectDir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Load JRX configuration
+	jrxConfig, err := config.ReadJRXConfig()
+	if err != nil {
+		fmt.Printf("Error reading JRX config: %v\n", err)
+		return
+	}
+
+	tm, err := stateManager(jrxConfig, state)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Compare against the recorded version unless another one was requested
+	label := version
+	if version == "" {
+		label = state.Template.Version
+		version, err = loadRecordedVersion(tm, state)
+	} else if version, err = tm.ResolveVersion(version); err == nil {
+		err = tm.LoadTemplates(version)
+	}
+	if err != nil {
+		fmt.Printf("Error loading templates: %v\n", err)
+		return
+	}
+
+	rendered, _, err := renderVersion(tm, state, version)
+	if err != nil {
+		fmt.Printf("Error rendering template: %v\n", err)
+		return
+	}
+
+	var filesChanged, insertions, deletions int
+	var statPaths, statLines []string
+	for _, file := range rendered {
+		if !matchPaths(file.Path, paths) {
+			continue
+		}
+
+		local, err := generator.ReadProjectFile(filepath.Join(projectDir, file.Path))
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Error reading %s: %v\n", file.Path, err)
+			return
+		}
+		if bytes.Equal(local, file.Content) {
+			continue
+		}
+		filesChanged++
+
+		oldName, newName := "a/"+file.Path, "b/"+file.Path
+		if err != nil {
+			newName = "/dev/null"
+		}
+
+		if generator.IsBinary(local) || generator.IsBinary(file.Content) {
+			if stat {
+				statPaths = append(statPaths, file.Path)
+				statLines = append(statLines, "Bin")
+			} else {
+				fmt.Printf("Binary files %s and %s differ\n", oldName, newName)
+			}
+			continue
+		}
+
+		templateLines := diff.Lines(string(file.Content))
+		projectLines := diff.Lines(string(local))
+
+		if stat {
+			added, removed := diff.Stat(templateLines, projectLines)
+			insertions += added
+			deletions += removed
+			statPaths = append(statPaths, file.Path)
+			statLines = append(statLines, fmt.Sprintf("%d %s%s", added+removed, strings.Repeat("+", min(added, 40)), strings.Repeat("-", min(removed, 40))))
+			continue
+		}
+
+		fmt.Print(diff.Unified(oldName, newName, templateLines, projectLines, 3))
+	}
+
+	if stat {
+		width := 0
+		for _, path := range statPaths {
+			width = max(width, len(path))
+		}
+		for i, path := range statPaths {
+			fmt.Printf(" %-*s | %s\n", width, path, statLines[i])
+		}
+		fmt.Printf(" %d files changed, %d insertions(+), %d deletions(-)\n", filesChanged, insertions, deletions)
+	}
+	if filesChanged == 0 && !stat {
+		fmt.Printf("Project '%s' matches template '%s' (%s)\n", state.Project.Name, state.Template.Name, label)
+	}
+}
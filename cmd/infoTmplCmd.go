@@ -2,12 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"log"
+	"strings"
 
 	"github.com/navigator-systems/jrx/internal/config"
 	"github.com/navigator-systems/jrx/internal/templates"
 )
 
-func TmplInfoCmd(version string) {
+func TmplInfoCmd(source, version string) {
 	// Load JRX configuration
 	jrxConfig, err := config.ReadJRXConfig()
 	if err != nil {
@@ -15,10 +17,36 @@ func TmplInfoCmd(version string) {
 		return
 	}
 
-	// Create template manager
-	tm := templates.NewTemplateManager(jrxConfig)
-	if version == "" {
-		version = jrxConfig.TemplatesDefault
+	sources, err := templates.NewSources(jrxConfig)
+	if err != nil {
+		log.Printf("Warning: could not load all template sources: %v\n", err)
+	}
+
+	// List a single source when one is given, every source otherwise
+	names := sources.Names()
+	if source != "" {
+		names = []string{source}
+	}
+	for _, name := range names {
+		tm, err := sources.Get(name)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if name != "" {
+			fmt.Printf("\n== Source: %s ==\n", name)
+		}
+		listTemplates(tm, version)
+	}
+	fmt.Println("\nUse 'jrx project new <project_name> <template_name>' to create a new project from a template.")
+}
+
+// listTemplates prints the templates of a template source at a version
+func listTemplates(tm *templates.TemplateManager, version string) {
+	version, err := tm.ResolveVersion(version)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
 	}
 
 	fmt.Println("Template version is: ", version)
@@ -38,17 +66,37 @@ func TmplInfoCmd(version string) {
 	fmt.Println("Available templates:")
 	for _, tmpl := range tmplList {
 		fmt.Printf("\nName: %s\n", tmpl.Name)
+		fmt.Printf("  Use: %s\n", tmpl.QualifiedName())
 		fmt.Printf("  Path: %s\n", tmpl.Path)
 		fmt.Printf("  Description: %s\n", tmpl.Description)
 		if len(tmpl.Tags) > 0 {
 			fmt.Printf("  Tags: %v\n", tmpl.Tags)
 		}
+		if tmpl.Extends != "" {
+			fmt.Printf("  Extends: %s\n", tmpl.Extends)
+		}
+		if len(tmpl.Mixins) > 0 {
+			fmt.Printf("  Mixins: %s\n", strings.Join(tmpl.Mixins, ", "))
+		}
 		if len(tmpl.Variables) > 0 {
 			fmt.Println("  Variables:")
 			for _, v := range tmpl.Variables {
-				fmt.Printf("    - %s: %s (default: '%s')\n", v.Key, v.Description, v.Default)
+				required := ""
+				if v.Required {
+					required = ", required"
+				}
+				if v.Expr != "" {
+					fmt.Printf("    - %s (%s%s): %s (computed: '%s')\n", v.Key, v.VarType(), required, v.Description, v.Expr)
+				} else {
+					fmt.Printf("    - %s (%s%s): %s (default: '%s')\n", v.Key, v.VarType(), required, v.Description, v.Default)
+				}
+				if len(v.Choices) > 0 {
+					fmt.Printf("        choices: %s\n", strings.Join(v.Choices, ", "))
+				}
+				if v.Help != "" {
+					fmt.Printf("        %s\n", v.Help)
+				}
 			}
 		}
 	}
-	fmt.Println("\nUse 'jrx project new <project_name> <template_name>' to create a new project from a template.")
 }
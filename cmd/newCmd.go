@@ -1,37 +1,84 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/navigator-systems/jrx/internal/adapters/scm"
 	"github.com/navigator-systems/jrx/internal/config"
 	"github.com/navigator-systems/jrx/internal/errors"
 	"github.com/navigator-systems/jrx/internal/generator"
 	"github.com/navigator-systems/jrx/internal/templates"
+	"github.com/navigator-systems/jrx/internal/vars"
 )
 
-func parseVars(varsString string) map[string]string {
-	vars := make(map[string]string)
-	if varsString == "" {
-		return vars
-	}
-	// Split por comas
-	pairs := strings.Split(varsString, ",")
-	for _, pair := range pairs {
-		// Split por =
-		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
-		if len(kv) == 2 {
-			key := strings.TrimSpace(kv[0])
-			value := strings.Trim(strings.TrimSpace(kv[1]), "\"'") // Remove quotes
-			vars[key] = value
+// NewOptions holds the options of 'jrx project new'
+type NewOptions struct {
+	Vars        string   // Legacy key1=value1,key2=value2 list
+	VarFlags    []string // Repeated key=value assignments
+	VarsFile    string   // TOML, YAML or JSON file with variable values
+	GithubOrg   string
+	GitlabGroup string
+	Provider    string
+	Namespace   string
+	Version     string
+	NoInput     bool   // Never prompt for variables, fail on missing required values
+	AllowHooks  bool   // Run template hooks executing commands without asking
+	NoHooks     bool   // Never run template hooks executing commands
+	DryRun      bool   // Render everything in memory and report, without writing
+	Show        string // With DryRun, print the rendered content of this path
+}
+
+// resolveProvider picks the SCM provider and namespace requested on the command line.
+// --github-organization and --gitlab-group are shortcuts for the first provider of that kind.
+func resolveProvider(cfg config.JRXConfig, opts NewOptions) (scm.Provider, string, error) {
+	selected := 0
+	for _, flag := range []string{opts.GithubOrg, opts.GitlabGroup, opts.Provider} {
+		if flag != "" {
+			selected++
 		}
 	}
-	return vars
+	if selected == 0 {
+		return nil, "", nil
+	}
+	if selected > 1 {
+		return nil, "", fmt.Errorf("use only one of --github-organization, --gitlab-group or --provider")
+	}
+
+	registry, err := scm.NewRegistry(cfg)
+	if err != nil {
+		log.Printf("Warning: could not load all SCM providers: %v\n", err)
+	}
+
+	switch {
+	case opts.GithubOrg != "":
+		provider, err := registry.Find("github", opts.GithubOrg)
+		return provider, opts.GithubOrg, err
+	case opts.GitlabGroup != "":
+		provider, err := registry.Find("gitlab", opts.GitlabGroup)
+		return provider, opts.GitlabGroup, err
+	}
+
+	provider, err := registry.Get(opts.Provider)
+	if err != nil {
+		return nil, "", err
+	}
+	namespace := opts.Namespace
+	if namespace == "" {
+		if len(provider.Namespaces()) != 1 {
+			return nil, "", fmt.Errorf("--namespace is required, provider %s has %d namespaces", opts.Provider, len(provider.Namespaces()))
+		}
+		namespace = provider.Namespaces()[0]
+	}
+	return provider, namespace, nil
 }
 
-func NewCmd(projectName, templateName, varsString, githubOrg, version string) {
+func NewCmd(projectName, templateName string, opts NewOptions) {
 	// Validate input
 	if projectName == "" {
 		fmt.Println("Error:", errors.ErrEmptyProjectName)
@@ -49,11 +96,29 @@ func NewCmd(projectName, templateName, varsString, githubOrg, version string) {
 		return
 	}
 
-	// Create template manager
-	tm := templates.NewTemplateManager(jrxConfig)
+	// Resolve the remote provider before generating anything
+	provider, namespace, err := resolveProvider(jrxConfig, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Find the template source, templates of [template_sources] are addressed as <source>/<template>
+	sources, err := templates.NewSources(jrxConfig)
+	if err != nil {
+		log.Printf("Warning: could not load all template sources: %v\n", err)
+	}
+	tm, templateKey, err := sources.Resolve(templateName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
 
-	if version == "" {
-		version = jrxConfig.TemplatesDefault
+	// Resolve the default version and the latest alias
+	version, err := tm.ResolveVersion(opts.Version)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
 	}
 
 	// Load templates
@@ -63,7 +128,7 @@ func NewCmd(projectName, templateName, varsString, githubOrg, version string) {
 	}
 
 	// Get the specific template
-	tmpl, err := tm.GetTemplate(templateName)
+	tmpl, err := tm.GetTemplate(templateKey)
 	if err != nil {
 		if err.Error() == "get template: template not found" {
 			fmt.Printf("Template '%s' not found\n", templateName)
@@ -73,26 +138,49 @@ func NewCmd(projectName, templateName, varsString, githubOrg, version string) {
 		return
 	}
 
-	// Parse Variables
-	userVars := parseVars(varsString)
+	// Computed defaults may refer to the project name
+	tmpl.ProjectName = projectName
 
-	if len(userVars) > 0 {
-		//
-		for i := range tmpl.Variables {
-			fmt.Println(i)
-			if userValue, exists := userVars[tmpl.Variables[i].Key]; exists {
-				tmpl.Variables[i].Default = userValue
-				log.Printf("Variable '%s' set to: %s\n", tmpl.Variables[i].Key, userValue)
-			}
+	// Collect the variable values from the vars file, the environment and the flags
+	userVars, err := loadVariables(tmpl, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Ask for the variables that were not supplied. Every question reads from the same
+	// buffered reader, a second one would lose the input the first already buffered.
+	stdin := bufio.NewReader(os.Stdin)
+	if !opts.NoInput && isInteractive() {
+		if err := promptVariables(tmpl, userVars, stdin, os.Stdout); err != nil {
+			fmt.Printf("\nError: %v\n", err)
+			return
 		}
 	}
 
+	// Apply and validate the variables before anything is written
+	if err := tmpl.ApplyVariables(userVars); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
 	// Create project generator
 	pg := generator.NewProjectGenerator(
-		tmpl, projectName, tm.GetTemplatesDir(), version, tm.GetFuncMap(), jrxConfig)
+		tmpl, projectName, tm.GetTemplatesDir(), version, tm.GetFuncMap(), tm.Config())
+
+	if opts.DryRun {
+		pg.SetHookPolicy(generator.HookPolicy{Actions: true, Commands: !opts.NoHooks})
+		previewProject(pg, templateName, version, provider, namespace, opts.Show)
+		return
+	}
+
+	// Template hooks running commands need the user's consent
+	pg.SetHookPolicy(generator.HookPolicy{Actions: true, Commands: allowHookCommands(tmpl, opts, stdin)})
 
 	// Generate the project
-	if err := pg.Generate(); err != nil {
+	err = pg.Generate()
+	printHookResults(pg.HookResults(), true)
+	if err != nil {
 		fmt.Printf("Error generating project: %v\n", err)
 		return
 	}
@@ -100,11 +188,11 @@ func NewCmd(projectName, templateName, varsString, githubOrg, version string) {
 	fmt.Printf("Project '%s' created successfully from template '%s'\n", projectName, templateName)
 	log.Printf("Project directory: %s\n", pg.GetOutputDir())
 
-	if githubOrg != "" {
-		// Create GitHub repository
+	if provider != nil {
+		// Create the remote repository
 		ctx := context.Background()
-		if err := pg.CreateAndPushToGitHub(ctx, githubOrg); err != nil {
-			fmt.Printf("Warning: Failed to create/push GitHub repository: %v\n", err)
+		if _, err := pg.CreateAndPush(ctx, provider, namespace); err != nil {
+			fmt.Printf("Warning: Failed to create/push %s repository: %v\n", provider.Name(), err)
 			fmt.Printf("Project was created locally. You can push manually:\n")
 			fmt.Printf("  cd %s\n", pg.GetOutputDir())
 			fmt.Printf("  git remote add origin <repo-url>\n")
@@ -113,3 +201,159 @@ func NewCmd(projectName, templateName, varsString, githubOrg, version string) {
 		}
 	}
 }
+
+// loadVariables merges the variable values given by the user, from lowest to highest precedence:
+// the vars file, JRX_VAR_* environment variables, --vars and --var flags.
+// Template defaults apply to whatever is left unset.
+func loadVariables(tmpl *templates.RootTemplate, opts NewOptions) (map[string]string, error) {
+	fileVars := map[string]string{}
+	if opts.VarsFile != "" {
+		var err error
+		if fileVars, err = vars.LoadFile(opts.VarsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := make([]string, 0, len(tmpl.Variables))
+	for _, variable := range tmpl.Variables {
+		keys = append(keys, variable.Key)
+	}
+	envVars := vars.FromEnv(keys, os.LookupEnv)
+
+	listVars, err := vars.ParseList(opts.Vars)
+	if err != nil {
+		return nil, err
+	}
+	flagVars, err := vars.ParseAssignments(opts.VarFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	return vars.Merge(fileVars, envVars, listVars, flagVars), nil
+}
+
+// allowHookCommands decides whether the hook commands of the template may run,
+// asking in interactive terminals unless a flag already decided
+func allowHookCommands(tmpl *templates.RootTemplate, opts NewOptions, stdin *bufio.Reader) bool {
+	hooks := tmpl.CommandHooks()
+	switch {
+	case len(hooks) == 0 || opts.NoHooks:
+		return false
+	case opts.AllowHooks:
+		return true
+	}
+
+	if opts.NoInput || !isInteractive() {
+		fmt.Printf("Skipping %d template hook command(s), use --allow-hooks to run them\n", len(hooks))
+		return false
+	}
+
+	fmt.Printf("Template '%s' wants to run these commands:\n", tmpl.Name)
+	for _, hook := range hooks {
+		fmt.Printf("  - %s\n", strings.Join(hook.Run, " "))
+	}
+	answer, err := readAnswer(stdin, os.Stdout, "Run them? [y/N]: ")
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+// printHookResults prints the outcome of the template hooks, with the command output if requested
+func printHookResults(results []generator.HookResult, output bool) {
+	if len(results) == 0 {
+		return
+	}
+	fmt.Println("\nHooks:")
+	for _, result := range results {
+		line := fmt.Sprintf("  [%s] %s: %s", result.Stage, result.Name, result.Status)
+		if result.Message != "" {
+			line += " (" + result.Message + ")"
+		}
+		fmt.Println(line)
+		if output && result.Output != "" {
+			for _, outputLine := range strings.Split(strings.TrimRight(result.Output, "\n"), "\n") {
+				fmt.Printf("      %s\n", outputLine)
+			}
+		}
+	}
+}
+
+// previewProject prints what generating the project would produce, without writing anything
+func previewProject(pg *generator.ProjectGenerator, templateName, version string, provider scm.Provider, namespace, show string) {
+	report, err := pg.Preview()
+	if err != nil {
+		fmt.Printf("Error previewing project: %v\n", err)
+		return
+	}
+
+	if show != "" {
+		for _, file := range report.Files {
+			if file.Path == filepath.Clean(show) {
+				switch {
+				case file.IsSymlink():
+					fmt.Printf("%s -> %s\n", file.Path, file.Content)
+				case generator.IsBinary(file.Content):
+					fmt.Printf("%s: binary file, %d bytes\n", file.Path, len(file.Content))
+				default:
+					fmt.Print(string(file.Content))
+				}
+				return
+			}
+		}
+		for _, renderErr := range report.Errors {
+			if renderErr.Path == filepath.Clean(show) {
+				fmt.Printf("Error: %s\n", renderErr.Error)
+				return
+			}
+		}
+		fmt.Printf("Error: '%s' is not generated by template '%s'\n", show, templateName)
+		return
+	}
+
+	fmt.Printf("Dry run: project '%s' from template '%s' (%s)\n", pg.GetProjectName(), templateName, version)
+	if pg.OutputExists() {
+		fmt.Printf("Warning: directory '%s' already exists, generation would fail\n", pg.GetOutputDir())
+	}
+
+	var total int
+	fmt.Println("\nFiles that would be created:")
+	for _, file := range report.Files {
+		if file.IsSymlink() {
+			fmt.Printf("  %s -> %s\n", file.Path, file.Content)
+			continue
+		}
+		total += len(file.Content)
+		details := fmt.Sprintf("%d bytes", len(file.Content))
+		if generator.IsBinary(file.Content) {
+			details += ", binary"
+		}
+		if file.Mode&0111 != 0 {
+			details += ", executable"
+		}
+		fmt.Printf("  %s (%s)\n", file.Path, details)
+	}
+
+	if len(report.Skipped) > 0 {
+		fmt.Println("\nSkipped:")
+		for _, skipped := range report.Skipped {
+			fmt.Printf("  %s (%s)\n", skipped.Path, skipped.Reason)
+		}
+	}
+
+	printHookResults(report.Hooks, false)
+
+	if len(report.Errors) > 0 {
+		fmt.Println("\nTemplate errors:")
+		for _, renderErr := range report.Errors {
+			fmt.Printf("  %s: %s\n", renderErr.Path, renderErr.Error)
+		}
+	}
+
+	if provider != nil {
+		fmt.Printf("\nWould create repository '%s' in %s (%s)\n", pg.GetProjectName(), namespace, provider.Name())
+	}
+
+	fmt.Printf("\n%d files, %d bytes, %d errors. Nothing was written.\n", len(report.Files), total, len(report.Errors))
+}
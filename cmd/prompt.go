@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/navigator-systems/jrx/internal/templates"
+)
+
+// isInteractive reports whether stdin is a terminal a user can answer prompts on
+func isInteractive() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// promptVariables asks for the value of every template variable not present in values.
+// Answers are validated and asked again until they are accepted.
+func promptVariables(tmpl *templates.RootTemplate, values map[string]string, reader *bufio.Reader, out io.Writer) error {
+	for _, variable := range tmpl.Variables {
+		if _, supplied := values[variable.Key]; supplied {
+			continue
+		}
+
+		// Offer the computed default given the answers so far
+		if variable.Expr != "" && variable.Default == "" {
+			if computed, err := tmpl.ComputeValues(values); err == nil {
+				variable.Default = computed[variable.Key]
+			}
+		}
+
+		for {
+			value, err := promptVariable(variable, reader, out)
+			if err != nil {
+				return err
+			}
+			if err := variable.Check(value); err != nil {
+				fmt.Fprintf(out, "  %v\n", err)
+				continue
+			}
+			values[variable.Key] = value
+			break
+		}
+	}
+	return nil
+}
+
+// promptVariable asks for one value, returning the default when the answer is empty
+func promptVariable(variable templates.VariablesTemplate, reader *bufio.Reader, out io.Writer) (string, error) {
+	label := variable.Key
+	if variable.Description != "" {
+		label = fmt.Sprintf("%s - %s", variable.Key, variable.Description)
+	}
+	if variable.Help != "" {
+		fmt.Fprintf(out, "%s\n", variable.Help)
+	}
+
+	switch {
+	case len(variable.Choices) > 0:
+		fmt.Fprintf(out, "%s:\n", label)
+		defaultChoice := ""
+		for i, choice := range variable.Choices {
+			fmt.Fprintf(out, "  %d) %s\n", i+1, choice)
+			if choice == variable.Default {
+				defaultChoice = strconv.Itoa(i + 1)
+			}
+		}
+		answer, err := readAnswer(reader, out, fmt.Sprintf("Choose%s: ", bracket(defaultChoice)))
+		if err != nil || answer == "" {
+			return variable.Default, err
+		}
+		// Accept the number of the choice as well as the value itself
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(variable.Choices) {
+			return variable.Choices[n-1], nil
+		}
+		return answer, nil
+
+	case variable.VarType() == templates.VarTypeBool:
+		hint := "[y/N]"
+		if on, _ := strconv.ParseBool(variable.Default); on {
+			hint = "[Y/n]"
+		}
+		answer, err := readAnswer(reader, out, fmt.Sprintf("%s? %s: ", label, hint))
+		if err != nil || answer == "" {
+			return variable.Default, err
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return "true", nil
+		case "n", "no":
+			return "false", nil
+		}
+		return answer, nil
+
+	default:
+		answer, err := readAnswer(reader, out, fmt.Sprintf("%s%s: ", label, bracket(variable.Default)))
+		if err != nil || answer == "" {
+			return variable.Default, err
+		}
+		return answer, nil
+	}
+}
+
+// readAnswer prints the prompt and reads one trimmed line
+func readAnswer(reader *bufio.Reader, out io.Writer, prompt string) (string, error) {
+	fmt.Fprint(out, prompt)
+	line, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("reading answer: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// bracket formats a default value as " [value]", or nothing when empty
+func bracket(value string) string {
+	if value == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [%s]", value)
+}
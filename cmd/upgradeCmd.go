@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/navigator-systems/jrx/internal/adapters/scm"
+	"github.com/navigator-systems/jrx/internal/config"
+	"github.com/navigator-systems/jrx/internal/generator"
+	"github.com/navigator-systems/jrx/internal/templates"
+)
+
+// renderVersion renders the template recorded in state at a template version, in memory
+func renderVersion(tm *templates.TemplateManager, state generator.ProjectState, version string) ([]generator.RenderedFile, *generator.ProjectGenerator, error) {
+	tmpl, err := tm.GetTemplate(state.Template.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("template '%s' (version %s): %w", state.Template.Name, version, err)
+	}
+	tmpl.SetVariables(state.Variables)
+
+	// Variables added to the template since generation take their computed default
+	tmpl.ProjectName = state.Project.Name
+	if err := tmpl.ResolveComputed(state.Variables); err != nil {
+		return nil, nil, err
+	}
+
+	pg := generator.NewProjectGenerator(tmpl, state.Project.Name, tm.GetTemplatesDir(), version, tm.GetFuncMap(), tm.Config())
+	files, err := pg.Render()
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, pg, nil
+}
+
+// loadRecordedVersion loads the templates exactly as they were when the project was generated.
+// If the cached version moved since then, the recorded commit is checked out instead.
+func loadRecordedVersion(tm *templates.TemplateManager, state generator.ProjectState) (string, error) {
+	if state.Template.Commit == "" {
+		log.Printf("Warning: no commit recorded, using the current '%s' templates as base\n", state.Template.Version)
+		return state.Template.Version, tm.LoadTemplates(state.Template.Version)
+	}
+
+	commit, err := templates.ResolveRevision(tm.GetTemplatesDir(), state.Template.Version)
+	if err == nil && commit == state.Template.Commit {
+		return state.Template.Version, tm.LoadTemplates(state.Template.Version)
+	}
+
+	return tm.LoadRevision(state.Template.Commit)
+}
+
+// stateManager returns the template manager of the source the project was generated from
+func stateManager(cfg config.JRXConfig, state generator.ProjectState) (*templates.TemplateManager, error) {
+	sources, err := templates.NewSources(cfg)
+	if err != nil {
+		log.Printf("Warning: could not load all template sources: %v\n", err)
+	}
+	return sources.Get(state.Template.Source)
+}
+
+func UpgradeCmd(projectDir, version string, force bool) {
+	if projectDir == "" {
+		projectDir = "."
+	}
+
+	// Read the provenance recorded at generation time
+	state, err := generator.ReadProjectState(projectDir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Refuse to merge into uncommitted work unless forced
+	if _, err := os.Stat(filepath.Join(projectDir, ".git")); err == nil && !force {
+		clean, err := scm.GitIsClean(projectDir)
+		if err != nil {
+			fmt.Printf("Error checking git status: %v\n", err)
+			return
+		}
+		if !clean {
+			fmt.Println("Error: the project has uncommitted changes, commit or stash them first (or use --force)")
+			return
+		}
+	}
+
+	// Load JRX configuration
+	jrxConfig, err := config.ReadJRXConfig()
+	if err != nil {
+		fmt.Printf("Error reading JRX config: %v\n", err)
+		return
+	}
+
+	tm, err := stateManager(jrxConfig, state)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if version, err = tm.ResolveVersion(version); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Render the target version first to know whether there is anything to do
+	if err := tm.LoadTemplates(version); err != nil {
+		fmt.Printf("Error loading templates: %v\n", err)
+		return
+	}
+	target, pg, err := renderVersion(tm, state, version)
+	if err != nil {
+		fmt.Printf("Error rendering template version %s: %v\n", version, err)
+		return
+	}
+
+	newState := pg.UpgradedState(state)
+	if newState.Template.Commit != "" && newState.Template.Commit == state.Template.Commit {
+		fmt.Printf("Project '%s' is already up to date with template '%s' (%s)\n", state.Project.Name, state.Template.Name, version)
+		return
+	}
+
+	// Render the recorded version as the merge base
+	baseVersion, err := loadRecordedVersion(tm, state)
+	if err != nil {
+		fmt.Printf("Error loading recorded template version %s: %v\n", state.Template.Version, err)
+		return
+	}
+	base, _, err := renderVersion(tm, state, baseVersion)
+	if err != nil {
+		fmt.Printf("Error rendering recorded template version: %v\n", err)
+		return
+	}
+
+	result, err := generator.ApplyUpgrade(projectDir, base, target, fmt.Sprintf("%s %s", state.Template.Name, version))
+	if err != nil {
+		fmt.Printf("Error applying upgrade: %v\n", err)
+		return
+	}
+
+	if err := generator.WriteProjectState(projectDir, newState); err != nil {
+		fmt.Printf("Error updating project state: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Upgraded '%s' from %s to %s\n", state.Project.Name, state.Template.Version, version)
+	printUpgradeFiles("Added", result.Added)
+	printUpgradeFiles("Updated", result.Updated)
+	printUpgradeFiles("Removed", result.Removed)
+	printUpgradeFiles("Kept (changed locally)", result.Skipped)
+	printUpgradeFiles("Conflicts", result.Conflicts)
+	if len(result.Conflicts) > 0 {
+		fmt.Println("\nResolve the conflict markers, then review and commit the upgrade.")
+	}
+}
+
+// printUpgradeFiles prints a titled list of files, skipping empty lists
+func printUpgradeFiles(title string, files []string) {
+	if len(files) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	for _, file := range files {
+		fmt.Printf("  - %s\n", file)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 )
 
@@ -95,25 +96,50 @@ func GitAddRemote(repoPath, remoteName, remoteURL string) error {
 	return nil
 }
 
-// GitPush pushes the local repository to the remote
-func GitPush(repoPath string, remoteName string, branch string, sshKeyPath string, sshKeyPassphrase string) error {
+// GitIsClean reports whether the repository at repoPath has no uncommitted changes
+func GitIsClean(repoPath string) (bool, error) {
 	repo, err := git.PlainOpen(repoPath)
 	if err != nil {
-		return fmt.Errorf("failed to open repository: %w", err)
+		return false, fmt.Errorf("failed to open repository: %w", err)
 	}
 
+	w, err := repo.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	status, err := w.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	return status.IsClean(), nil
+}
+
+// GitPush pushes the local repository to the remote
+func GitPush(repoPath string, remoteName string, branch string, sshKeyPath string, sshKeyPassphrase string) error {
 	// Setup SSH authentication
 	publicKeys, err := ssh.NewPublicKeysFromFile("git", sshKeyPath, sshKeyPassphrase)
 	if err != nil {
 		return fmt.Errorf("failed to create SSH keys: %w", err)
 	}
 
+	return GitPushWithAuth(repoPath, remoteName, branch, publicKeys)
+}
+
+// GitPushWithAuth pushes the local repository to the remote with the given authentication
+func GitPushWithAuth(repoPath string, remoteName string, branch string, auth transport.AuthMethod) error {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
 	// Push to remote
 	refSpec := config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/heads/%s", branch, branch))
 	err = repo.Push(&git.PushOptions{
 		RemoteName: remoteName,
 		RefSpecs:   []config.RefSpec{refSpec},
-		Auth:       publicKeys,
+		Auth:       auth,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to push: %w", err)
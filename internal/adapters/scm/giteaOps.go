@@ -0,0 +1,144 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/navigator-systems/jrx/internal/config"
+)
+
+// GiteaClient talks to the Gitea REST API, which Forgejo implements as well
+type GiteaClient struct {
+	providerBase
+	baseURL string
+	client  *http.Client
+}
+
+// giteaRepository is the subset of the Gitea repository payload jrx needs
+type giteaRepository struct {
+	Name     string `json:"name"`
+	HTMLURL  string `json:"html_url"`
+	SSHURL   string `json:"ssh_url"`
+	CloneURL string `json:"clone_url"`
+}
+
+// NewGiteaClient creates a new Gitea/Forgejo provider from its configuration
+func NewGiteaClient(name string, pc config.JRXProviderConfig, cfg config.JRXConfig) (Provider, error) {
+	if pc.Token == "" {
+		return nil, fmt.Errorf("gitea token not found in config")
+	}
+	if pc.URL == "" {
+		return nil, fmt.Errorf("gitea url not found in config")
+	}
+
+	base, err := newProviderBase(name, strings.ToLower(pc.Type), "oauth2", pc, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := strings.TrimSuffix(strings.TrimSpace(pc.URL), "/")
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "https://" + baseURL
+	}
+
+	return &GiteaClient{
+		providerBase: base,
+		baseURL:      baseURL,
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// do sends an authenticated API request and decodes the JSON response into out (if not nil)
+func (gc *GiteaClient) do(ctx context.Context, method, path string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, gc.baseURL+"/api/v1"+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "token "+gc.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := gc.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// CreateRepository creates a new repository in the organization
+func (gc *GiteaClient) CreateRepository(ctx context.Context, org, repoName, description string, private bool) (*Repository, error) {
+	if err := gc.checkNamespace(org); err != nil {
+		return nil, err
+	}
+
+	request := map[string]any{
+		"name":           repoName,
+		"description":    description,
+		"private":        private,
+		"auto_init":      false, // Don't initialize with README yet
+		"default_branch": "main",
+	}
+
+	var created giteaRepository
+	if _, err := gc.do(ctx, http.MethodPost, "/orgs/"+url.PathEscape(org)+"/repos", request, &created); err != nil {
+		return nil, fmt.Errorf("failed to create repository: %w", err)
+	}
+
+	return &Repository{
+		Name:      created.Name,
+		Namespace: org,
+		HTMLURL:   created.HTMLURL,
+		SSHURL:    created.SSHURL,
+		HTTPSURL:  created.CloneURL,
+	}, nil
+}
+
+// RepositoryExists checks whether the repository already exists in the organization
+func (gc *GiteaClient) RepositoryExists(ctx context.Context, org, repoName string) (bool, error) {
+	status, err := gc.do(ctx, http.MethodGet, "/repos/"+url.PathEscape(org)+"/"+url.PathEscape(repoName), nil, nil)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get repository: %w", err)
+	}
+	return true, nil
+}
+
+// DeleteRepository deletes the repository from the organization
+func (gc *GiteaClient) DeleteRepository(ctx context.Context, org, repoName string) error {
+	if _, err := gc.do(ctx, http.MethodDelete, "/repos/"+url.PathEscape(org)+"/"+url.PathEscape(repoName), nil, nil); err != nil {
+		return fmt.Errorf("failed to delete repository: %w", err)
+	}
+	return nil
+}
@@ -3,7 +3,7 @@ package scm
 import (
 	"context"
 	"fmt"
-	"slices"
+	"net/http"
 
 	"github.com/google/go-github/v58/github"
 	"github.com/navigator-systems/jrx/internal/config"
@@ -12,31 +12,29 @@ import (
 
 // GitHubClient wraps the GitHub client with configuration
 type GitHubClient struct {
+	providerBase
 	client *github.Client
-	org    string
 }
 
-// NewGitHubClient creates a new GitHub client from JRX config
-func NewGitHubClient(cfg config.JRXConfig, githubOrg string) (*GitHubClient, error) {
-	if cfg.GitProvider.GithubToken == "" {
-		return nil, fmt.Errorf("github_token not found in config")
+// NewGitHubClient creates a new GitHub provider from its configuration
+func NewGitHubClient(name string, pc config.JRXProviderConfig, cfg config.JRXConfig) (Provider, error) {
+	if pc.Token == "" {
+		return nil, fmt.Errorf("github token not found in config")
 	}
 
-	if slices.Contains(cfg.GitProvider.GithubOrganization, githubOrg) {
-		// Ok
-	} else {
-		return nil, fmt.Errorf("github_organization %s not found in config", githubOrg)
+	base, err := newProviderBase(name, "github", "x-access-token", pc, cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: cfg.GitProvider.GithubToken},
+		&oauth2.Token{AccessToken: pc.Token},
 	)
 	tc := oauth2.NewClient(ctx, ts)
 
 	var client *github.Client
-	var err error
-	domain := cfg.GitProvider.GithubURL
+	domain := pc.URL
 	// If it's not github.com, use Enterprise client
 	if domain != "" && domain != "github.com" {
 		baseURL := fmt.Sprintf("https://%s/api/v3/", domain)
@@ -49,13 +47,17 @@ func NewGitHubClient(cfg config.JRXConfig, githubOrg string) (*GitHubClient, err
 	}
 
 	return &GitHubClient{
-		client: client,
-		org:    githubOrg,
+		providerBase: base,
+		client:       client,
 	}, nil
 }
 
 // CreateRepository creates a new repository in the organization
-func (gc *GitHubClient) CreateRepository(ctx context.Context, repoName string, description string, private bool) (*github.Repository, error) {
+func (gc *GitHubClient) CreateRepository(ctx context.Context, org, repoName, description string, private bool) (*Repository, error) {
+	if err := gc.checkNamespace(org); err != nil {
+		return nil, err
+	}
+
 	repo := &github.Repository{
 		Name:        github.String(repoName),
 		Description: github.String(description),
@@ -63,10 +65,36 @@ func (gc *GitHubClient) CreateRepository(ctx context.Context, repoName string, d
 		AutoInit:    github.Bool(false), // Don't initialize with README yet
 	}
 
-	createdRepo, _, err := gc.client.Repositories.Create(ctx, gc.org, repo)
+	createdRepo, _, err := gc.client.Repositories.Create(ctx, org, repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create repository: %w", err)
 	}
 
-	return createdRepo, nil
+	return &Repository{
+		Name:      createdRepo.GetName(),
+		Namespace: org,
+		HTMLURL:   createdRepo.GetHTMLURL(),
+		SSHURL:    createdRepo.GetSSHURL(),
+		HTTPSURL:  createdRepo.GetCloneURL(),
+	}, nil
+}
+
+// RepositoryExists checks whether the repository already exists in the organization
+func (gc *GitHubClient) RepositoryExists(ctx context.Context, org, repoName string) (bool, error) {
+	_, resp, err := gc.client.Repositories.Get(ctx, org, repoName)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get repository: %w", err)
+	}
+	return true, nil
+}
+
+// DeleteRepository deletes the repository from the organization
+func (gc *GitHubClient) DeleteRepository(ctx context.Context, org, repoName string) error {
+	if _, err := gc.client.Repositories.Delete(ctx, org, repoName); err != nil {
+		return fmt.Errorf("failed to delete repository: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,115 @@
+package scm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/navigator-systems/jrx/internal/config"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// GitLabClient wraps the GitLab client with configuration
+type GitLabClient struct {
+	providerBase
+	client *gitlab.Client
+}
+
+// NewGitLabClient creates a new GitLab provider from its configuration
+func NewGitLabClient(name string, pc config.JRXProviderConfig, cfg config.JRXConfig) (Provider, error) {
+	if pc.Token == "" {
+		return nil, fmt.Errorf("gitlab token not found in config")
+	}
+
+	base, err := newProviderBase(name, "gitlab", "oauth2", pc, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var options []gitlab.ClientOptionFunc
+	// If it's not gitlab.com, point the client to the self-managed instance
+	if baseURL := gitlabBaseURL(pc.URL); baseURL != "" {
+		options = append(options, gitlab.WithBaseURL(baseURL))
+	}
+
+	client, err := gitlab.NewClient(pc.Token, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gitlab client: %w", err)
+	}
+
+	return &GitLabClient{
+		providerBase: base,
+		client:       client,
+	}, nil
+}
+
+// gitlabBaseURL normalizes the configured GitLab URL, accepting either a bare domain or a full URL
+func gitlabBaseURL(gitlabURL string) string {
+	gitlabURL = strings.TrimSuffix(strings.TrimSpace(gitlabURL), "/")
+	if gitlabURL == "" || gitlabURL == "gitlab.com" || gitlabURL == "https://gitlab.com" {
+		return ""
+	}
+	if !strings.Contains(gitlabURL, "://") {
+		gitlabURL = "https://" + gitlabURL
+	}
+	return gitlabURL
+}
+
+// CreateRepository creates a new project in the group (or subgroup)
+func (gc *GitLabClient) CreateRepository(ctx context.Context, group, projectName, description string, private bool) (*Repository, error) {
+	if err := gc.checkNamespace(group); err != nil {
+		return nil, err
+	}
+
+	// Resolve the namespace ID from the full group path, e.g. "platform/services"
+	glGroup, _, err := gc.client.Groups.GetGroup(group, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group %s: %w", group, err)
+	}
+
+	visibility := gitlab.PublicVisibility
+	if private {
+		visibility = gitlab.PrivateVisibility
+	}
+
+	project, _, err := gc.client.Projects.CreateProject(&gitlab.CreateProjectOptions{
+		Name:                 gitlab.Ptr(projectName),
+		Path:                 gitlab.Ptr(projectName),
+		NamespaceID:          gitlab.Ptr(glGroup.ID),
+		Description:          gitlab.Ptr(description),
+		Visibility:           gitlab.Ptr(visibility),
+		InitializeWithReadme: gitlab.Ptr(false), // Don't initialize with README yet
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project: %w", err)
+	}
+
+	return &Repository{
+		Name:      project.Path,
+		Namespace: group,
+		HTMLURL:   project.WebURL,
+		SSHURL:    project.SSHURLToRepo,
+		HTTPSURL:  project.HTTPURLToRepo,
+	}, nil
+}
+
+// RepositoryExists checks whether the project already exists in the group
+func (gc *GitLabClient) RepositoryExists(ctx context.Context, group, projectName string) (bool, error) {
+	_, resp, err := gc.client.Projects.GetProject(group+"/"+projectName, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get project: %w", err)
+	}
+	return true, nil
+}
+
+// DeleteRepository deletes the project from the group
+func (gc *GitLabClient) DeleteRepository(ctx context.Context, group, projectName string) error {
+	if _, err := gc.client.Projects.DeleteProject(group+"/"+projectName, nil, gitlab.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+	return nil
+}
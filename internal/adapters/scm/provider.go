@@ -0,0 +1,235 @@
+package scm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/navigator-systems/jrx/internal/config"
+)
+
+// Repository describes a remote repository created by a provider
+type Repository struct {
+	Name      string
+	Namespace string
+	HTMLURL   string
+	SSHURL    string
+	HTTPSURL  string
+}
+
+// Provider is implemented by every SCM backend jrx can create repositories on
+type Provider interface {
+	// Name returns the name the provider was registered with in .jrxrc
+	Name() string
+	// Kind returns the provider type (github, gitlab, gitea...)
+	Kind() string
+	// Namespaces returns the organizations or groups repositories may be created in
+	Namespaces() []string
+	// CreateRepository creates an empty repository in the namespace
+	CreateRepository(ctx context.Context, namespace, name, description string, private bool) (*Repository, error)
+	// RepositoryExists reports whether namespace/name already exists
+	RepositoryExists(ctx context.Context, namespace, name string) (bool, error)
+	// DeleteRepository removes namespace/name, used to roll back a failed creation
+	DeleteRepository(ctx context.Context, namespace, name string) error
+	// CloneURL resolves the URL used to push to the repository
+	CloneURL(repo *Repository) string
+	// Push pushes the main branch of the local repository to the remote
+	Push(repoPath string, repo *Repository) error
+}
+
+// ProviderFactory builds a provider from its configuration
+type ProviderFactory func(name string, pc config.JRXProviderConfig, cfg config.JRXConfig) (Provider, error)
+
+// factories holds the provider types jrx knows how to build
+var factories = map[string]ProviderFactory{
+	"github":  NewGitHubClient,
+	"gitlab":  NewGitLabClient,
+	"gitea":   NewGiteaClient,
+	"forgejo": NewGiteaClient,
+}
+
+// Target is a provider/namespace pair a project can be pushed to
+type Target struct {
+	Provider  string
+	Kind      string
+	Namespace string
+}
+
+// Registry holds the configured providers by name
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry builds every provider declared in the JRX config
+func NewRegistry(cfg config.JRXConfig) (*Registry, error) {
+	registry := &Registry{providers: make(map[string]Provider)}
+
+	var errs []string
+	for name, pc := range cfg.ProviderConfigs() {
+		factory, ok := factories[strings.ToLower(pc.Type)]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("provider %s: unknown type '%s'", name, pc.Type))
+			continue
+		}
+		provider, err := factory(name, pc, cfg)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("provider %s: %v", name, err))
+			continue
+		}
+		registry.providers[name] = provider
+	}
+
+	if len(errs) > 0 {
+		return registry, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return registry, nil
+}
+
+// Get returns the provider registered under name
+func (r *Registry) Get(name string) (Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("provider %s not found in config", name)
+	}
+	return provider, nil
+}
+
+// Find returns the first provider of the given kind allowed to use namespace.
+// A provider registered with the kind as name is preferred.
+func (r *Registry) Find(kind, namespace string) (Provider, error) {
+	if provider, ok := r.providers[kind]; ok && provider.Kind() == kind && slices.Contains(provider.Namespaces(), namespace) {
+		return provider, nil
+	}
+	for _, name := range r.Names() {
+		provider := r.providers[name]
+		if provider.Kind() == kind && slices.Contains(provider.Namespaces(), namespace) {
+			return provider, nil
+		}
+	}
+	return nil, fmt.Errorf("no %s provider configured for %s", kind, namespace)
+}
+
+// Names returns the registered provider names sorted alphabetically
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Targets returns every provider/namespace pair available for project creation
+func (r *Registry) Targets() []Target {
+	var targets []Target
+	for _, name := range r.Names() {
+		provider := r.providers[name]
+		for _, namespace := range provider.Namespaces() {
+			targets = append(targets, Target{
+				Provider:  name,
+				Kind:      provider.Kind(),
+				Namespace: namespace,
+			})
+		}
+	}
+	return targets
+}
+
+// providerBase implements the parts of Provider shared by every backend
+type providerBase struct {
+	name             string
+	kind             string
+	namespaces       []string
+	protocol         string
+	token            string
+	httpsUser        string
+	sshKeyPath       string
+	sshKeyPassphrase string
+}
+
+// newProviderBase reads the common provider settings, falling back to the global SSH key
+func newProviderBase(name, kind, httpsUser string, pc config.JRXProviderConfig, cfg config.JRXConfig) (providerBase, error) {
+	base := providerBase{
+		name:             name,
+		kind:             kind,
+		namespaces:       pc.Namespaces,
+		protocol:         strings.ToLower(pc.Protocol),
+		token:            pc.Token,
+		httpsUser:        httpsUser,
+		sshKeyPath:       pc.SshKeyPath,
+		sshKeyPassphrase: pc.SshKeyPassphrase,
+	}
+	if base.protocol == "" {
+		base.protocol = "ssh"
+	}
+	if base.protocol != "ssh" && base.protocol != "https" {
+		return base, fmt.Errorf("unknown protocol '%s', expected ssh or https", pc.Protocol)
+	}
+	if base.sshKeyPath == "" {
+		base.sshKeyPath = cfg.SshKeyPath
+		base.sshKeyPassphrase = cfg.SshKeyPassphrase
+	}
+	return base, nil
+}
+
+// Name returns the name the provider was registered with
+func (b *providerBase) Name() string {
+	return b.name
+}
+
+// Kind returns the provider type
+func (b *providerBase) Kind() string {
+	return b.kind
+}
+
+// Namespaces returns the allowed organizations or groups
+func (b *providerBase) Namespaces() []string {
+	return b.namespaces
+}
+
+// checkNamespace verifies the namespace is allowed for this provider
+func (b *providerBase) checkNamespace(namespace string) error {
+	if !slices.Contains(b.namespaces, namespace) {
+		return fmt.Errorf("namespace %s not found in config for provider %s", namespace, b.name)
+	}
+	return nil
+}
+
+// CloneURL returns the SSH or HTTPS URL depending on the configured protocol
+func (b *providerBase) CloneURL(repo *Repository) string {
+	if b.protocol == "https" {
+		return repo.HTTPSURL
+	}
+	return repo.SSHURL
+}
+
+// auth returns the transport authentication matching the configured protocol
+func (b *providerBase) auth() (transport.AuthMethod, error) {
+	if b.protocol == "https" {
+		return &http.BasicAuth{Username: b.httpsUser, Password: b.token}, nil
+	}
+	publicKeys, err := ssh.NewPublicKeysFromFile("git", b.sshKeyPath, b.sshKeyPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH keys: %w", err)
+	}
+	return publicKeys, nil
+}
+
+// Push adds the remote and pushes the main branch
+func (b *providerBase) Push(repoPath string, repo *Repository) error {
+	if err := GitAddRemote(repoPath, "origin", b.CloneURL(repo)); err != nil {
+		return err
+	}
+
+	auth, err := b.auth()
+	if err != nil {
+		return err
+	}
+
+	return GitPushWithAuth(repoPath, "origin", "main", auth)
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/BurntSushi/toml"
 )
@@ -10,19 +13,53 @@ import (
 // This config is used for jrx to know about the templates repository and the server configuration
 // This is not for reading each jrx project, but for the jrx system itself
 type JRXConfig struct {
-	TemplatesRepo        string   `toml:"templates_repo"`
-	TemplatesDefault     string   `toml:"templates_default"`
-	TemplatesBranch      []string `toml:"templates_branches"`
-	TemplatesPatternGlob string   `toml:"templates_version_pattern"`
-	TemplatesMaxVersions int      `toml:"templates_max_versions"`
-	TemplatesTag         []string `toml:"templates_tags"`
-	TemplatesCacheDir    string   `toml:"templates_cache_dir,omitempty"` // Cache directory for templates
+	TemplatesRepo              string                       `toml:"templates_repo"`
+	TemplatesDefault           string                       `toml:"templates_default"`
+	TemplatesBranch            []string                     `toml:"templates_branches"`
+	TemplatesPatternGlob       string                       `toml:"templates_version_pattern"`
+	TemplatesMaxVersions       int                          `toml:"templates_max_versions"`
+	TemplatesVersionConstraint string                       `toml:"templates_version_constraint,omitempty"` // Semantic version range of the tags to cache
+	TemplatesPrerelease        bool                         `toml:"templates_prerelease,omitempty"`         // Also cache pre-release tags
+	TemplatesTag               []string                     `toml:"templates_tags"`
+	TemplatesCacheDir          string                       `toml:"templates_cache_dir,omitempty"` // Cache directory for templates
+	TemplatesDir               string                       `toml:"templates_dir,omitempty"`       // Local templates directory, read directly instead of the cache
+	TemplateSources            map[string]JRXTemplateSource `toml:"template_sources,omitempty"`    // Additional template repositories by name
+	TemplatesSource            string                       `toml:"-"`                             // Name of the source the templates_* settings describe, "" for the top-level one
+	TemplateEnvAllow           []string                     `toml:"template_env_allow,omitempty"`  // Environment variables templates may read with env (globs)
 
-	SshKeyPath       string         `toml:"ssh_key_path"`
-	SshKeyPassphrase string         `toml:"ssh_key_passphrase,omitempty"`
-	ServerPort       string         `toml:"server_port"`
-	GitProvider      JRXGitProvider `toml:"git_provider"`
-	Database         JRXDataBase    `toml:"data_base"`
+	SshKeyPath       string                       `toml:"ssh_key_path"`
+	SshKeyPassphrase string                       `toml:"ssh_key_passphrase,omitempty"`
+	ServerPort       string                       `toml:"server_port"`
+	ServerHooks      string                       `toml:"server_hooks,omitempty"` // Template hooks the server runs: none, actions (default) or all
+	GitProvider      JRXGitProvider               `toml:"git_provider"`
+	Providers        map[string]JRXProviderConfig `toml:"providers,omitempty"` // SCM providers registered by name
+	Database         JRXDataBase                  `toml:"data_base"`
+}
+
+// JRXTemplateSource describes one more templates repository, with its own versions, access and cache
+type JRXTemplateSource struct {
+	Repo              string   `toml:"repo,omitempty"`               // Git URL, file:// URL or local working copy
+	Dir               string   `toml:"dir,omitempty"`                // Local templates directory, read directly
+	Default           string   `toml:"default,omitempty"`            // Default version, defaults to the first branch or main
+	Branches          []string `toml:"branches,omitempty"`           // Branches to cache, defaults to the default version
+	VersionPattern    string   `toml:"version_pattern,omitempty"`    // Glob the cached tags must match
+	VersionConstraint string   `toml:"version_constraint,omitempty"` // Semantic version range of the cached tags
+	Prerelease        bool     `toml:"prerelease,omitempty"`         // Also cache pre-release tags
+	MaxVersions       int      `toml:"max_versions,omitempty"`       // Number of most recent tags to cache
+	CacheDir          string   `toml:"cache_dir,omitempty"`          // Defaults to <templates_cache_dir>/.sources/<name>
+	SshKeyPath        string   `toml:"ssh_key_path,omitempty"`       // Overrides the global ssh_key_path
+	SshKeyPassphrase  string   `toml:"ssh_key_passphrase,omitempty"` // Overrides the global ssh_key_passphrase
+}
+
+// JRXProviderConfig describes one SCM provider instance (a GitHub Enterprise host, a GitLab, a Gitea...)
+type JRXProviderConfig struct {
+	Type             string   `toml:"type"`                         // github, gitlab, gitea or forgejo
+	URL              string   `toml:"url,omitempty"`                // Domain or base URL, defaults to the public SaaS
+	Token            string   `toml:"token,omitempty"`              // API token used to create repositories
+	Namespaces       []string `toml:"namespaces,omitempty"`         // Organizations or groups repositories may be created in
+	Protocol         string   `toml:"protocol,omitempty"`           // ssh (default) or https
+	SshKeyPath       string   `toml:"ssh_key_path,omitempty"`       // Overrides the global ssh_key_path
+	SshKeyPassphrase string   `toml:"ssh_key_passphrase,omitempty"` // Overrides the global ssh_key_passphrase
 }
 
 type JRXDataBase struct {
@@ -40,8 +77,116 @@ type JRXGitProvider struct {
 	GithubURL          string   `toml:"github_url,omitempty"`
 	GithubOrganization []string `toml:"github_organization_url,omitempty"`
 
-	GitlabToken string `toml:"gitlab_token,omitempty"`
-	GitlabGroup string `toml:"gitlab_group,omitempty"`
+	GitlabToken  string   `toml:"gitlab_token,omitempty"`
+	GitlabURL    string   `toml:"gitlab_url,omitempty"` // Base URL for self-managed GitLab, defaults to gitlab.com
+	GitlabGroup  string   `toml:"gitlab_group,omitempty"`
+	GitlabGroups []string `toml:"gitlab_groups,omitempty"` // Additional groups or subgroups (full paths)
+}
+
+// GitlabGroupList returns every GitLab group jrx is allowed to create projects in
+func (gp JRXGitProvider) GitlabGroupList() []string {
+	groups := make([]string, 0, len(gp.GitlabGroups)+1)
+	if gp.GitlabGroup != "" {
+		groups = append(groups, gp.GitlabGroup)
+	}
+	for _, group := range gp.GitlabGroups {
+		if group != "" && group != gp.GitlabGroup {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// ProviderConfigs returns every configured SCM provider by name.
+// The legacy [git_provider] section is exposed as the "github" and "gitlab" providers
+// unless a provider with the same name is declared in [providers].
+func (c JRXConfig) ProviderConfigs() map[string]JRXProviderConfig {
+	providers := make(map[string]JRXProviderConfig, len(c.Providers)+2)
+
+	gp := c.GitProvider
+	if gp.GithubToken != "" || len(gp.GithubOrganization) > 0 {
+		providers["github"] = JRXProviderConfig{
+			Type:       "github",
+			URL:        gp.GithubURL,
+			Token:      gp.GithubToken,
+			Namespaces: gp.GithubOrganization,
+		}
+	}
+	if gp.GitlabToken != "" || len(gp.GitlabGroupList()) > 0 {
+		providers["gitlab"] = JRXProviderConfig{
+			Type:       "gitlab",
+			URL:        gp.GitlabURL,
+			Token:      gp.GitlabToken,
+			Namespaces: gp.GitlabGroupList(),
+		}
+	}
+
+	for name, provider := range c.Providers {
+		providers[name] = provider
+	}
+	return providers
+}
+
+// TemplateSourceNames returns the names of the template sources sorted alphabetically.
+// The source of the top-level templates_* settings is named "" and comes first, it is left
+// out when only [template_sources] are configured.
+func (c JRXConfig) TemplateSourceNames() []string {
+	var names []string
+	if c.TemplatesRepo != "" || c.TemplatesDir != "" || len(c.TemplateSources) == 0 {
+		names = append(names, "")
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.TemplateSources)) {
+		names = append(names, name)
+	}
+	return names
+}
+
+// SourceConfig returns the configuration with the templates_* settings of a template source
+func (c JRXConfig) SourceConfig(name string) (JRXConfig, error) {
+	if name == "" {
+		return c, nil
+	}
+	source, ok := c.TemplateSources[name]
+	if !ok {
+		return c, fmt.Errorf("template source '%s' is not configured", name)
+	}
+
+	cfg := c
+	cfg.TemplatesSource = name
+	cfg.TemplatesRepo = source.Repo
+	cfg.TemplatesDir = source.Dir
+	cfg.TemplatesBranch = source.Branches
+	cfg.TemplatesDefault = source.Default
+	if cfg.TemplatesDefault == "" {
+		cfg.TemplatesDefault = "main"
+		if len(cfg.TemplatesBranch) > 0 {
+			cfg.TemplatesDefault = cfg.TemplatesBranch[0]
+		}
+	}
+	if len(cfg.TemplatesBranch) == 0 {
+		cfg.TemplatesBranch = []string{cfg.TemplatesDefault}
+	}
+	cfg.TemplatesPatternGlob = source.VersionPattern
+	cfg.TemplatesVersionConstraint = source.VersionConstraint
+	cfg.TemplatesPrerelease = source.Prerelease
+	cfg.TemplatesMaxVersions = source.MaxVersions
+	cfg.TemplatesCacheDir = source.CacheDir
+	if cfg.TemplatesCacheDir == "" {
+		cfg.TemplatesCacheDir = filepath.Join(c.TemplatesCacheDir, ".sources", name)
+	}
+	if source.SshKeyPath != "" {
+		cfg.SshKeyPath = source.SshKeyPath
+		cfg.SshKeyPassphrase = source.SshKeyPassphrase
+	}
+	return cfg, nil
+}
+
+// templatesDirOverride is set by --templates-dir and replaces templates_dir
+var templatesDirOverride string
+
+// SetTemplatesDir makes ReadJRXConfig read templates from a local directory
+func SetTemplatesDir(dir string) {
+	templatesDirOverride = dir
 }
 
 func ReadJRXConfig() (JRXConfig, error) {
@@ -53,6 +198,9 @@ func ReadJRXConfig() (JRXConfig, error) {
 	if err != nil {
 		return jrxConfig, err
 	}
+	if templatesDirOverride != "" {
+		jrxConfig.TemplatesDir = templatesDirOverride
+	}
 
 	return jrxConfig, nil
 }
@@ -0,0 +1,110 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Op is the kind of a diff chunk
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Chunk is a run of lines that are equal, inserted in b or deleted from a
+type Chunk struct {
+	Op    Op
+	Lines []string
+}
+
+// Lines splits text into lines, keeping the line terminators
+func Lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Compute returns the line based edit script turning a into b
+func Compute(a, b []string) []Chunk {
+	runesA, runesB := linesToRunes(a, b)
+
+	dmp := diffmatchpatch.New()
+	dmp.DiffTimeout = 0 // Always compute the minimal diff
+	diffs := dmp.DiffMainRunes(runesA, runesB, false)
+
+	chunks := make([]Chunk, 0, len(diffs))
+	ia, ib := 0, 0
+	for _, d := range diffs {
+		n := len([]rune(d.Text))
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			chunks = append(chunks, Chunk{Op: Equal, Lines: a[ia : ia+n]})
+			ia += n
+			ib += n
+		case diffmatchpatch.DiffDelete:
+			chunks = append(chunks, Chunk{Op: Delete, Lines: a[ia : ia+n]})
+			ia += n
+		case diffmatchpatch.DiffInsert:
+			chunks = append(chunks, Chunk{Op: Insert, Lines: b[ib : ib+n]})
+			ib += n
+		}
+	}
+	return chunks
+}
+
+// linesToRunes maps every distinct line to a rune so the diff runs on lines instead of characters
+func linesToRunes(a, b []string) ([]rune, []rune) {
+	ids := make(map[string]rune)
+	next := rune(1)
+	encode := func(lines []string) []rune {
+		runes := make([]rune, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = next
+				ids[line] = id
+				next++
+				// Skip the UTF-16 surrogate range, those are not valid runes
+				if next == 0xD800 {
+					next = 0xE000
+				}
+			}
+			runes[i] = id
+		}
+		return runes
+	}
+	return encode(a), encode(b)
+}
+
+// matches maps every line of a that is kept in b to its index in b (-1 otherwise)
+func matches(a, b []string) []int {
+	matched := make([]int, len(a))
+	ia, ib := 0, 0
+	for _, chunk := range Compute(a, b) {
+		switch chunk.Op {
+		case Equal:
+			for range chunk.Lines {
+				matched[ia] = ib
+				ia++
+				ib++
+			}
+		case Delete:
+			for range chunk.Lines {
+				matched[ia] = -1
+				ia++
+			}
+		case Insert:
+			ib += len(chunk.Lines)
+		}
+	}
+	return matched
+}
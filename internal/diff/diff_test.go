@@ -0,0 +1,127 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a\n"}},
+		{"a\nb", []string{"a\n", "b"}},
+		{"a\n\nb\n", []string{"a\n", "\n", "b\n"}},
+	}
+	for _, tt := range tests {
+		if got := Lines(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Lines(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Chunk
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: []Chunk{{Equal, []string{"a\n", "b\n"}}},
+		},
+		{
+			name: "both empty",
+			want: []Chunk{},
+		},
+		{
+			name: "replace a line",
+			a:    "a\nb\nc\n",
+			b:    "a\nB\nc\n",
+			want: []Chunk{
+				{Equal, []string{"a\n"}},
+				{Delete, []string{"b\n"}},
+				{Insert, []string{"B\n"}},
+				{Equal, []string{"c\n"}},
+			},
+		},
+		{
+			name: "insert at EOF",
+			a:    "a\n",
+			b:    "a\nb\n",
+			want: []Chunk{
+				{Equal, []string{"a\n"}},
+				{Insert, []string{"b\n"}},
+			},
+		},
+		{
+			name: "newline added at EOF",
+			a:    "a\nb",
+			b:    "a\nb\n",
+			want: []Chunk{
+				{Equal, []string{"a\n"}},
+				{Delete, []string{"b"}},
+				{Insert, []string{"b\n"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(Lines(tt.a), Lines(tt.b))
+			if !slices.EqualFunc(got, tt.want, func(x, y Chunk) bool {
+				return x.Op == y.Op && slices.Equal(x.Lines, y.Lines)
+			}) {
+				t.Errorf("Compute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStat(t *testing.T) {
+	insertions, deletions := Stat(Lines("a\nb\nc\n"), Lines("a\nB\nc\nd\n"))
+	if insertions != 2 || deletions != 1 {
+		t.Errorf("Stat() = +%d -%d, want +2 -1", insertions, deletions)
+	}
+}
+
+func TestLinesToRunesSkipsSurrogates(t *testing.T) {
+	// Enough distinct lines to run the ids past the start of the surrogate range
+	a := make([]string, 0xD800+10)
+	for i := range a {
+		a[i] = fmt.Sprintf("line %d\n", i)
+	}
+	b := append(slices.Clone(a[1:]), "last\n")
+
+	runesA, runesB := linesToRunes(a, b)
+	seen := make(map[rune]bool)
+	for _, r := range append(runesA, runesB...) {
+		if r >= 0xD800 && r < 0xE000 {
+			t.Fatalf("linesToRunes() produced surrogate %U", r)
+		}
+		seen[r] = true
+	}
+	if len(seen) != len(a)+1 {
+		t.Fatalf("linesToRunes() produced %d distinct ids, want %d", len(seen), len(a)+1)
+	}
+
+	// Lines mapped past the surrogate range still diff correctly
+	var got []string
+	for _, chunk := range Compute(a, b) {
+		if chunk.Op != Equal {
+			for _, line := range chunk.Lines {
+				got = append(got, fmt.Sprintf("%d %s", chunk.Op, strings.TrimSpace(line)))
+			}
+		}
+	}
+	want := []string{fmt.Sprintf("%d line 0", Delete), fmt.Sprintf("%d last", Insert)}
+	if !slices.Equal(got, want) {
+		t.Errorf("Compute() changes = %q, want %q", got, want)
+	}
+}
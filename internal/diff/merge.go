@@ -0,0 +1,77 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// MergeResult is the outcome of a three-way merge
+type MergeResult struct {
+	Lines     []string
+	Conflicts int
+}
+
+// Text returns the merged lines joined back together
+func (mr MergeResult) Text() string {
+	return strings.Join(mr.Lines, "")
+}
+
+// Merge3 merges the changes made from base to ours and from base to theirs (diff3).
+// Overlapping changes that differ are written between conflict markers labelled
+// with oursLabel and theirsLabel.
+func Merge3(base, ours, theirs []string, oursLabel, theirsLabel string) MergeResult {
+	toOurs := matches(base, ours)
+	toTheirs := matches(base, theirs)
+
+	var result MergeResult
+	o, a, b := 0, 0, 0
+	for o < len(base) || a < len(ours) || b < len(theirs) {
+		// Stable line: kept by both sides at the current position
+		if o < len(base) && toOurs[o] == a && toTheirs[o] == b {
+			result.Lines = append(result.Lines, base[o])
+			o, a, b = o+1, a+1, b+1
+			continue
+		}
+
+		// Find the next base line kept by both sides, the chunk before it is unstable
+		next := o
+		for next < len(base) && (toOurs[next] < 0 || toTheirs[next] < 0) {
+			next++
+		}
+		endOurs, endTheirs := len(ours), len(theirs)
+		if next < len(base) {
+			endOurs, endTheirs = toOurs[next], toTheirs[next]
+		}
+
+		baseChunk := base[o:next]
+		oursChunk := ours[a:endOurs]
+		theirsChunk := theirs[b:endTheirs]
+
+		switch {
+		case slices.Equal(oursChunk, baseChunk):
+			result.Lines = append(result.Lines, theirsChunk...)
+		case slices.Equal(theirsChunk, baseChunk), slices.Equal(oursChunk, theirsChunk):
+			result.Lines = append(result.Lines, oursChunk...)
+		default:
+			result.Conflicts++
+			result.Lines = append(result.Lines, "<<<<<<< "+oursLabel+"\n")
+			result.Lines = append(result.Lines, terminated(oursChunk)...)
+			result.Lines = append(result.Lines, "=======\n")
+			result.Lines = append(result.Lines, terminated(theirsChunk)...)
+			result.Lines = append(result.Lines, ">>>>>>> "+theirsLabel+"\n")
+		}
+
+		o, a, b = next, endOurs, endTheirs
+	}
+	return result
+}
+
+// terminated makes sure the last line ends with a newline so conflict markers start on their own line
+func terminated(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+	lines = slices.Clone(lines)
+	lines[len(lines)-1] += "\n"
+	return lines
+}
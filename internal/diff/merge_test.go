@@ -0,0 +1,151 @@
+package diff
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		ours      string
+		theirs    string
+		want      string
+		conflicts int
+	}{
+		{
+			name:   "unchanged",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nb\nc\n",
+		},
+		{
+			name:   "only ours changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nB\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nB\nc\n",
+		},
+		{
+			name:   "only theirs changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nC\n",
+			want:   "a\nb\nC\n",
+		},
+		{
+			name:   "non-overlapping changes",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "A\nb\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   "A\nb\nc\nd\nE\n",
+		},
+		{
+			name:   "deletion and distant change",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "a\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   "a\nc\nd\nE\n",
+		},
+		{
+			name:   "identical changes on both sides",
+			base:   "a\nb\nc\n",
+			ours:   "a\nX\nc\n",
+			theirs: "a\nX\nc\n",
+			want:   "a\nX\nc\n",
+		},
+		{
+			name:   "identical deletions on both sides",
+			base:   "a\nb\nc\n",
+			ours:   "a\nc\n",
+			theirs: "a\nc\n",
+			want:   "a\nc\n",
+		},
+		{
+			name:      "overlapping conflict",
+			base:      "a\nb\nc\n",
+			ours:      "a\nours\nc\n",
+			theirs:    "a\ntheirs\nc\n",
+			want:      "a\n<<<<<<< local\nours\n=======\ntheirs\n>>>>>>> template\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "conflict against a deletion",
+			base:      "a\nb\nc\n",
+			ours:      "a\nc\n",
+			theirs:    "a\nB\nc\n",
+			want:      "a\n<<<<<<< local\n=======\nB\n>>>>>>> template\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "two separate conflicts",
+			base:      "a\nb\nc\nd\ne\n",
+			ours:      "A1\nb\nc\nd\nE1\n",
+			theirs:    "A2\nb\nc\nd\nE2\n",
+			want:      "<<<<<<< local\nA1\n=======\nA2\n>>>>>>> template\nb\nc\nd\n<<<<<<< local\nE1\n=======\nE2\n>>>>>>> template\n",
+			conflicts: 2,
+		},
+		{
+			name:   "insertion at EOF by theirs",
+			base:   "a\nb\n",
+			ours:   "A\nb\n",
+			theirs: "a\nb\nc\n",
+			want:   "A\nb\nc\n",
+		},
+		{
+			name:   "same insertion at EOF on both sides",
+			base:   "a\n",
+			ours:   "a\nb\n",
+			theirs: "a\nb\n",
+			want:   "a\nb\n",
+		},
+		{
+			name:      "different insertions at EOF",
+			base:      "a\n",
+			ours:      "a\nours\n",
+			theirs:    "a\ntheirs\n",
+			want:      "a\n<<<<<<< local\nours\n=======\ntheirs\n>>>>>>> template\n",
+			conflicts: 1,
+		},
+		{
+			name:   "empty base",
+			base:   "",
+			ours:   "",
+			theirs: "new\n",
+			want:   "new\n",
+		},
+		{
+			name:   "no trailing newline, change elsewhere",
+			base:   "a\nb\nc",
+			ours:   "A\nb\nc",
+			theirs: "a\nb\nc",
+			want:   "A\nb\nc",
+		},
+		{
+			name:   "no trailing newline, line appended by theirs",
+			base:   "a\nb",
+			ours:   "a\nb",
+			theirs: "a\nb\nc",
+			want:   "a\nb\nc",
+		},
+		{
+			name:      "no trailing newline in a conflict",
+			base:      "a\nb",
+			ours:      "a\nours",
+			theirs:    "a\ntheirs",
+			want:      "a\n<<<<<<< local\nours\n=======\ntheirs\n>>>>>>> template\n",
+			conflicts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Merge3(Lines(tt.base), Lines(tt.ours), Lines(tt.theirs), "local", "template")
+			if got := result.Text(); got != tt.want {
+				t.Errorf("Merge3() text = %q, want %q", got, tt.want)
+			}
+			if result.Conflicts != tt.conflicts {
+				t.Errorf("Merge3() conflicts = %d, want %d", result.Conflicts, tt.conflicts)
+			}
+		})
+	}
+}
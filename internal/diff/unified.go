@@ -0,0 +1,115 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// line is a single line of an edit script with its position in both texts
+type line struct {
+	op     Op
+	text   string
+	oldPos int
+	newPos int
+}
+
+// Stat returns the number of lines inserted and deleted between a and b
+func Stat(a, b []string) (insertions, deletions int) {
+	for _, chunk := range Compute(a, b) {
+		switch chunk.Op {
+		case Insert:
+			insertions += len(chunk.Lines)
+		case Delete:
+			deletions += len(chunk.Lines)
+		}
+	}
+	return insertions, deletions
+}
+
+// Unified returns the unified diff between a and b with the given lines of context.
+// It returns an empty string when both texts are equal.
+func Unified(oldName, newName string, a, b []string, context int) string {
+	var lines []line
+	oldPos, newPos := 0, 0
+	for _, chunk := range Compute(a, b) {
+		for _, text := range chunk.Lines {
+			lines = append(lines, line{op: chunk.Op, text: text, oldPos: oldPos, newPos: newPos})
+			switch chunk.Op {
+			case Equal:
+				oldPos++
+				newPos++
+			case Delete:
+				oldPos++
+			case Insert:
+				newPos++
+			}
+		}
+	}
+
+	var out strings.Builder
+	for start := 0; start < len(lines); {
+		// Find the next change
+		first := start
+		for first < len(lines) && lines[first].op == Equal {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+
+		// Extend the hunk while changes are closer than two contexts apart
+		last := first
+		for i := first; i < len(lines); i++ {
+			if lines[i].op != Equal {
+				last = i
+			} else if i-last > 2*context {
+				break
+			}
+		}
+
+		from := max(first-context, start)
+		to := min(last+context+1, len(lines))
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		writeHunk(&out, lines[from:to])
+		start = to
+	}
+	return out.String()
+}
+
+// writeHunk writes one @@ hunk of a unified diff
+func writeHunk(out *strings.Builder, hunk []line) {
+	oldStart, newStart := hunk[0].oldPos, hunk[0].newPos
+	oldLen, newLen := 0, 0
+	for _, l := range hunk {
+		if l.op != Insert {
+			oldLen++
+		}
+		if l.op != Delete {
+			newLen++
+		}
+	}
+	// Empty ranges point at the line before them, like GNU diff
+	if oldLen > 0 {
+		oldStart++
+	}
+	if newLen > 0 {
+		newStart++
+	}
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+
+	for _, l := range hunk {
+		prefix := " "
+		switch l.op {
+		case Insert:
+			prefix = "+"
+		case Delete:
+			prefix = "-"
+		}
+		out.WriteString(prefix + l.text)
+		if !strings.HasSuffix(l.text, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
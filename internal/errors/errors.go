@@ -44,4 +44,8 @@ var (
 	ErrCannotCloneBranch     = errors.New("cannot clone specified branch")
 	ErrLoadTemplates         = errors.New("failed to load templates")
 	ErrCannotCreateDirectory = errors.New("cannot create directory")
+	ErrRepositoryExists      = errors.New("remote repository already exists")
+	ErrStateNotFound         = errors.New("project state file .jrx/project.toml not found")
+	ErrUnknownVariable       = errors.New("unknown template variable")
+	ErrInvalidVariable       = errors.New("invalid template variable")
 )
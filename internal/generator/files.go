@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/navigator-systems/jrx/internal/errors"
+)
+
+// IsBinary reports whether content looks like binary data (a NUL byte in the first 8000 bytes, like git)
+func IsBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// ReadProjectFile returns the content of a project file, or the target of a symlink
+func ReadProjectFile(path string) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		return []byte(target), err
+	}
+	return os.ReadFile(path)
+}
+
+// writeProjectFile writes a rendered file to path with the mode of file. Symlinks are recreated.
+func writeProjectFile(path string, file RenderedFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.NewError("write project file", fmt.Errorf("failed to create directory: %w", err))
+	}
+
+	existing, statErr := os.Lstat(path)
+
+	if file.IsSymlink() {
+		if statErr == nil {
+			if err := os.Remove(path); err != nil {
+				return errors.NewError("write project file", err)
+			}
+		}
+		if err := os.Symlink(string(file.Content), path); err != nil {
+			return errors.NewError("write project file", err)
+		}
+		return nil
+	}
+
+	mode := file.Mode.Perm()
+	if mode == 0 {
+		mode = 0644
+	}
+	if statErr == nil && !existing.Mode().IsRegular() {
+		// A symlink replaced by a regular file
+		if err := os.Remove(path); err != nil {
+			return errors.NewError("write project file", err)
+		}
+	}
+
+	if err := os.WriteFile(path, file.Content, mode); err != nil {
+		return errors.NewError("write project file", err)
+	}
+	// WriteFile only applies the mode to new files
+	if err := os.Chmod(path, mode); err != nil {
+		return errors.NewError("write project file", err)
+	}
+	return nil
+}
+
+// upgradeMode returns the mode an upgrade gives to an existing project file: the mode of the
+// new template version when the template changed it and the local file still has the old one,
+// the local mode when the user changed it or the template left it alone
+func upgradeMode(local os.FileInfo, base RenderedFile, inBase bool, target RenderedFile) os.FileMode {
+	localMode := local.Mode().Perm()
+	if !local.Mode().IsRegular() || target.IsSymlink() {
+		return target.Mode
+	}
+	if !inBase || base.Mode.Perm() == target.Mode.Perm() || localMode != base.Mode.Perm() {
+		return localMode
+	}
+	return target.Mode
+}
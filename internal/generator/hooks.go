@@ -0,0 +1,306 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/navigator-systems/jrx/internal/errors"
+	"github.com/navigator-systems/jrx/internal/templates"
+	"github.com/navigator-systems/jrx/internal/vars"
+)
+
+// Hook stages
+const (
+	HookPre  = "pre"
+	HookPost = "post"
+)
+
+// Hook statuses reported in HookResult
+const (
+	HookOK      = "ok"
+	HookFailed  = "failed"
+	HookSkipped = "skipped"
+	HookDryRun  = "dry run"
+)
+
+// hookTimeout bounds the run time of a hook command
+const hookTimeout = 5 * time.Minute
+
+// hookWaitDelay bounds how long the output of a finished or killed hook command is still read,
+// so background processes it started and that keep its stdout open don't block generation
+const hookWaitDelay = 10 * time.Second
+
+// HookPolicy decides which template hooks a generation runs
+type HookPolicy struct {
+	Commands bool // Run hooks executing external commands
+	Actions  bool // Run built-in actions (chmod, rename, delete)
+}
+
+// HookResult is the outcome of one template hook
+type HookResult struct {
+	Stage   string `json:"stage"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"` // Why the hook was skipped or failed
+	Output  string `json:"output,omitempty"`  // Combined stdout and stderr of commands
+}
+
+// SetHookPolicy sets which template hooks Generate runs
+func (pg *ProjectGenerator) SetHookPolicy(policy HookPolicy) {
+	pg.hookPolicy = policy
+}
+
+// HookResults returns the outcome of the hooks run by the last Generate
+func (pg *ProjectGenerator) HookResults() []HookResult {
+	return pg.hookResults
+}
+
+// plannedHooks lists the hooks a generation would run, for previews
+func (pg *ProjectGenerator) plannedHooks() []HookResult {
+	var results []HookResult
+	for _, stage := range []string{HookPre, HookPost} {
+		for _, hook := range pg.stageHooks(stage) {
+			result := HookResult{Stage: stage, Name: hook.Label(), Status: HookDryRun}
+			if reason := pg.skipReason(hook); reason != "" {
+				result.Status, result.Message = HookSkipped, reason
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// stageHooks returns the hooks declared for a stage
+func (pg *ProjectGenerator) stageHooks(stage string) []templates.Hook {
+	if stage == HookPre {
+		return pg.template.ProjectInfo.Hooks.Pre
+	}
+	return pg.template.ProjectInfo.Hooks.Post
+}
+
+// skipReason tells why a hook won't run, or returns an empty string
+func (pg *ProjectGenerator) skipReason(hook templates.Hook) string {
+	switch {
+	case !pg.template.HookEnabled(hook):
+		return "conditions not met"
+	case hook.IsCommand() && !pg.hookPolicy.Commands:
+		return "commands not allowed"
+	case !hook.IsCommand() && !pg.hookPolicy.Actions:
+		return "actions not allowed"
+	}
+	return ""
+}
+
+// runHooks runs the hooks of a stage in order, stopping at the first failure.
+// Pre hooks run in a scratch directory, post hooks in the project.
+func (pg *ProjectGenerator) runHooks(stage string) error {
+	for _, hook := range pg.stageHooks(stage) {
+		result := HookResult{Stage: stage, Name: hook.Label()}
+
+		if reason := pg.skipReason(hook); reason != "" {
+			result.Status, result.Message = HookSkipped, reason
+			pg.hookResults = append(pg.hookResults, result)
+			continue
+		}
+
+		log.Printf("Running %s hook: %s\n", stage, hook.Label())
+		err := hook.Validate()
+		switch {
+		case err != nil:
+		case hook.IsCommand():
+			result.Output, err = pg.runCommand(stage, hook)
+		case stage == HookPre:
+			err = fmt.Errorf("built-in actions only run after rendering")
+		default:
+			err = pg.runAction(hook)
+		}
+
+		if err != nil {
+			result.Status, result.Message = HookFailed, err.Error()
+			pg.hookResults = append(pg.hookResults, result)
+			return errors.NewError(stage+" hook", fmt.Errorf("%s: %w", hook.Label(), err))
+		}
+		result.Status = HookOK
+		pg.hookResults = append(pg.hookResults, result)
+	}
+	return nil
+}
+
+// runCommand runs a hook command and returns its combined output.
+// Pre hooks set variables by printing JRX_VAR_<key>=value lines, the template must declare
+// them. They run in a temporary
+// directory: the template directory is shared by every generation from the cache, so it is
+// only passed as JRX_TEMPLATE_DIR, to be read.
+func (pg *ProjectGenerator) runCommand(stage string, hook templates.Hook) (string, error) {
+	args := make([]string, len(hook.Run))
+	for i, arg := range hook.Run {
+		rendered, err := pg.renderString(arg)
+		if err != nil {
+			return "", err
+		}
+		args[i] = rendered
+	}
+
+	templateDir, err := filepath.Abs(filepath.Join(templates.VersionDir(pg.templatesDir, pg.templateVersion), hook.Layer))
+	if err != nil {
+		return "", err
+	}
+	projectDir, err := filepath.Abs(pg.outputDir)
+	if err != nil {
+		return "", err
+	}
+
+	// Scripts shipped with the template are named relative to it
+	if stage == HookPre && !filepath.IsAbs(args[0]) && strings.ContainsRune(args[0], filepath.Separator) {
+		args[0] = filepath.Join(templateDir, args[0])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.WaitDelay = hookWaitDelay
+	cmd.Dir = projectDir
+	if stage == HookPre {
+		scratch, err := os.MkdirTemp("", "jrx-hook-")
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(scratch)
+		cmd.Dir = scratch
+	}
+	cmd.Env = append(os.Environ(),
+		"JRX_PROJECT_NAME="+pg.projectName,
+		"JRX_PROJECT_DIR="+projectDir,
+		"JRX_TEMPLATE_DIR="+templateDir,
+	)
+	for _, variable := range pg.template.Variables {
+		cmd.Env = append(cmd.Env, vars.EnvPrefix+variable.Key+"="+variable.Default)
+	}
+
+	// stdout and stderr are copied concurrently, the shared output buffer needs a lock
+	var output lockedBuffer
+	var stdout bytes.Buffer
+	cmd.Stdout = io.MultiWriter(&output, &stdout)
+	cmd.Stderr = &output
+	err = cmd.Run()
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		err = fmt.Errorf("timed out after %s", hookTimeout)
+	case stderrors.Is(err, exec.ErrWaitDelay):
+		// The command succeeded, a process it left in the background still held its output
+		log.Printf("Warning: hook %s left a background process running, its output was not read\n", hook.Label())
+		err = nil
+	}
+	if err != nil {
+		return output.buf.String(), err
+	}
+
+	if stage == HookPre {
+		values := make(map[string]string)
+		scanner := bufio.NewScanner(&stdout)
+		for scanner.Scan() {
+			if assignment, ok := strings.CutPrefix(scanner.Text(), vars.EnvPrefix); ok {
+				if key, value, err := vars.ParseAssignment(assignment); err == nil {
+					values[key] = value
+				}
+			}
+		}
+		// Hook values are explicit, computed defaults depending on them are evaluated again
+		if err := pg.template.ApplyVariables(values); err != nil {
+			return output.buf.String(), err
+		}
+	}
+	return output.buf.String(), nil
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// runAction runs a built-in action on the generated files
+func (pg *ProjectGenerator) runAction(hook templates.Hook) error {
+	path, err := pg.projectPath(hook.Path)
+	if err != nil {
+		return err
+	}
+
+	switch hook.Action {
+	case templates.HookActionDelete:
+		return os.RemoveAll(path)
+	case templates.HookActionChmod:
+		mode, err := strconv.ParseUint(hook.Mode, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid mode '%s'", hook.Mode)
+		}
+		return os.Chmod(path, os.FileMode(mode))
+	case templates.HookActionRename:
+		to, err := pg.projectPath(hook.To)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return err
+		}
+		return os.Rename(path, to)
+	}
+	return fmt.Errorf("unknown action '%s'", hook.Action)
+}
+
+// projectPath renders a hook path and resolves it inside the project directory.
+// The project directory itself is rejected, actions only apply to what it contains.
+func (pg *ProjectGenerator) projectPath(path string) (string, error) {
+	rendered, err := pg.renderString(path)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(rendered) == "" {
+		return "", fmt.Errorf("path is empty")
+	}
+	rendered = filepath.Clean(rendered)
+	if rendered == "." {
+		return "", fmt.Errorf("path %s is the project directory", path)
+	}
+	if !filepath.IsLocal(rendered) {
+		return "", fmt.Errorf("path %s is outside the project", rendered)
+	}
+	return filepath.Join(pg.outputDir, rendered), nil
+}
+
+// renderString executes the template expressions of a hook argument
+func (pg *ProjectGenerator) renderString(text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := pg.strict(template.New("hook").Funcs(pg.funcMap)).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	// Built from the current values, pre hooks may set variables
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, pg.renderContext()); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,36 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestProjectPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "bin/run.sh", want: filepath.Join("out", "bin", "run.sh")},
+		{path: "./docs/../README.md", want: filepath.Join("out", "README.md")},
+		{path: ".", wantErr: true},
+		{path: "./", wantErr: true},
+		{path: "docs/..", wantErr: true},
+		{path: "", wantErr: true},
+		{path: " ", wantErr: true},
+		{path: "../other", wantErr: true},
+		{path: "/etc/passwd", wantErr: true},
+	}
+	pg := &ProjectGenerator{outputDir: "out"}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := pg.projectPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("projectPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("projectPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,18 +1,22 @@
 package generator
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"text/template"
+	"time"
 
-	"github.com/google/go-github/v58/github"
 	"github.com/navigator-systems/jrx/internal/adapters/scm"
 	"github.com/navigator-systems/jrx/internal/config"
 	"github.com/navigator-systems/jrx/internal/errors"
 	"github.com/navigator-systems/jrx/internal/templates"
+	"github.com/navigator-systems/jrx/internal/version"
 )
 
 // ProjectGenerator handles project generation from templates
@@ -25,11 +29,17 @@ type ProjectGenerator struct {
 	templateVersion string
 	funcMap         template.FuncMap
 	config          config.JRXConfig
+	partials        *template.Template       // Shared {{define}} blocks, loaded by render
+	context         *templates.RenderContext // Data the files are rendered with, built by render
+	hookPolicy      HookPolicy
+	hookResults     []HookResult
 }
 
 var skipFiles = []string{
 	"project.toml",
 	"vars.toml",
+	templates.IgnoreFile,
+	templates.RevisionFile,
 }
 
 // NewProjectGenerator creates a new ProjectGenerator instance
@@ -47,6 +57,7 @@ func NewProjectGenerator(tmpl *templates.RootTemplate,
 		templateVersion: tmplVersion,
 		funcMap:         funcMap,
 		config:          cfg,
+		hookPolicy:      HookPolicy{Actions: true},
 	}
 }
 
@@ -55,20 +66,45 @@ func (pg *ProjectGenerator) SetOutputDir(dir string) {
 	pg.outputDir = dir
 }
 
-// Generate creates the project from the template
-func (pg *ProjectGenerator) Generate() error {
+// Generate creates the project from the template.
+// If a step fails once files were written, the output directory is removed so a retry can use the same name.
+func (pg *ProjectGenerator) Generate() (err error) {
 	// Validate project
-	final := filepath.Join(pg.templatesDir, pg.templateVersion)
+	final := templates.VersionDir(pg.templatesDir, pg.templateVersion)
 	log.Println("Generating project:", pg.projectName, "from template:", pg.template.Name, "folder:", final)
 	if err := pg.validateProject(); err != nil {
 		return err
 	}
 
+	// Validate or compute variables before anything is written
+	pg.hookResults = nil
+	if err := pg.runHooks(HookPre); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if cleanupErr := pg.CleanupLocalFiles(); cleanupErr != nil {
+				log.Printf("Warning: could not remove the partially generated project: %v\n", cleanupErr)
+			}
+		}
+	}()
+
 	// Copy and process template files
 	if err := pg.copyFiles(); err != nil {
 		return err
 	}
 
+	// Record template provenance
+	if err := pg.writeState(); err != nil {
+		return err
+	}
+
+	// Run the template's post-generation steps before the initial commit
+	if err := pg.runHooks(HookPost); err != nil {
+		return err
+	}
+
 	// Initialize Git repository
 	if err := pg.initializeGit(); err != nil {
 		return err
@@ -80,91 +116,414 @@ func (pg *ProjectGenerator) Generate() error {
 
 // validateProject checks if the project can be created
 func (pg *ProjectGenerator) validateProject() error {
-	if pg.projectName == "" {
-		return errors.NewError("validate project", errors.ErrEmptyProjectName)
+	if err := pg.validateTemplate(); err != nil {
+		return err
 	}
 
-	if pg.template == nil {
-		return errors.NewError("validate project", errors.ErrInvalidTemplate)
+	// Check if project directory already exists
+	if pg.OutputExists() {
+		return errors.NewError("validate project", errors.ErrProjectExists)
 	}
 
-	// Check if template path exists
+	return nil
+}
+
+// RenderedFile is a template file rendered in memory
+type RenderedFile struct {
+	Path    string      // Path relative to the project root
+	Content []byte      // File content, or the link target for symlinks
+	Mode    os.FileMode // Permission bits of the template file, with os.ModeSymlink for symlinks
+}
 
-	templatePath := pg.template.GetFullPath(pg.templatesDir, pg.templateVersion)
-	fmt.Println(templatePath)
-	if _, err := os.Stat(templatePath); err != nil {
-		return errors.NewError("validate project", errors.ErrTemplatePathMissing)
+// IsSymlink reports whether the file is a symbolic link
+func (f RenderedFile) IsSymlink() bool {
+	return f.Mode&os.ModeSymlink != 0
+}
+
+// SkippedFile is a template file that is not emitted into the project
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// RenderError is a template file that failed to parse or execute
+type RenderError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// RenderReport describes everything a generation produces, without writing anything
+type RenderReport struct {
+	Files   []RenderedFile
+	Skipped []SkippedFile
+	Errors  []RenderError
+	Hooks   []HookResult // Hooks a generation would run, nothing is executed in previews
+}
+
+// Render walks through the template directory and renders every file in memory.
+// It stops at the first template error.
+func (pg *ProjectGenerator) Render() ([]RenderedFile, error) {
+	report, err := pg.render(true)
+	if err != nil {
+		return nil, err
 	}
+	return report.Files, nil
+}
 
-	// Check if project directory already exists
-	if _, err := os.Stat(pg.outputDir); err == nil {
-		return errors.NewError("validate project", errors.ErrProjectExists)
+// Preview renders the whole template in memory, collecting template errors instead of
+// stopping at the first one. Nothing is written to disk.
+func (pg *ProjectGenerator) Preview() (*RenderReport, error) {
+	if err := pg.validateTemplate(); err != nil {
+		return nil, err
 	}
 
-	return nil
-}
+	report, err := pg.render(false)
+	if err != nil {
+		return nil, err
+	}
 
-// copyFiles walks through the template directory and processes all files
-func (pg *ProjectGenerator) copyFiles() error {
-	templatePath := pg.template.GetFullPath(pg.templatesDir, pg.templateVersion)
+	// The provenance manifest is part of the generated project as well
+	state, err := EncodeProjectState(pg.buildState())
+	if err != nil {
+		return nil, errors.NewError("encode project state", err)
+	}
+	report.Files = append(report.Files, RenderedFile{Path: filepath.Join(StateDir, StateFile), Content: state})
+	report.Hooks = pg.plannedHooks()
 
+	return report, nil
+}
+
+// render renders the template files into a report, failing fast on template errors if requested.
+// Composed templates are rendered layer by layer, files of later layers replacing earlier ones.
+func (pg *ProjectGenerator) render(failFast bool) (*RenderReport, error) {
 	// Set the project name in the template
 	pg.template.ProjectName = pg.projectName
 
-	err := filepath.Walk(templatePath, func(path string, info os.FileInfo, err error) error {
+	if err := pg.template.ValidateRules(); err != nil {
+		return nil, errors.NewError("validate template rules", err)
+	}
+
+	pg.context = pg.renderContext()
+
+	layers := pg.template.GetLayers()
+	partials, err := pg.loadPartials(layers)
+	if err != nil {
+		return nil, errors.NewError("load partials", err)
+	}
+	pg.partials = partials
+
+	report := &RenderReport{}
+	rendered := make(map[string]renderedSource)
+	for i, layer := range layers {
+		var prefix string
+		if len(layers) > 1 {
+			prefix = layer.Path
+		}
+		if err := pg.renderLayer(layer, i, prefix, report, rendered, failFast); err != nil {
+			return nil, errors.NewError("render template files", err)
+		}
+	}
+
+	slices.SortFunc(report.Files, func(a, b RenderedFile) int { return strings.Compare(a.Path, b.Path) })
+	return report, nil
+}
+
+// renderedSource is the template file a project file was rendered from
+type renderedSource struct {
+	layer int
+	path  string // Template path, prefixed with the layer for composed templates
+}
+
+// renderLayer renders the files of one template directory, the index-th layer, into the report.
+// prefix is prepended to the template paths shown in the report.
+func (pg *ProjectGenerator) renderLayer(layer templates.TemplateLayer, index int, prefix string, report *RenderReport, rendered map[string]renderedSource, failFast bool) error {
+	layerPath := filepath.Join(templates.VersionDir(pg.templatesDir, pg.templateVersion), layer.Path)
+
+	// Cached versions are symlinks to their tree, walk the tree itself
+	if resolved, err := filepath.EvalSymlinks(layerPath); err == nil {
+		layerPath = resolved
+	}
+	return filepath.Walk(layerPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		// Get the relative path to maintain directory structure
+		relPath, err := filepath.Rel(layerPath, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		if relPath == "." {
+			return nil
+		}
+		source := filepath.Join(prefix, relPath)
+
+		// Leave out the paths listed in the layer's .jrxignore
+		if layer.IsIgnored(relPath, info.IsDir()) {
+			report.Skipped = append(report.Skipped, SkippedFile{Path: source, Reason: "ignored by " + templates.IgnoreFile})
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Leave out the paths excluded by the template rules
+		if rule, excluded := pg.template.ExcludedBy(relPath, info.IsDir()); excluded {
+			report.Skipped = append(report.Skipped, SkippedFile{Path: source, Reason: "rule: " + rule.String()})
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// File and directory names may contain template expressions as well
+		destPath, err := pg.renderPath(relPath)
+		if err != nil {
+			if failFast {
+				return fmt.Errorf("error rendering path %s: %w", source, err)
+			}
+			report.Errors = append(report.Errors, RenderError{Path: source, Error: err.Error()})
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if destPath == "" {
+			report.Skipped = append(report.Skipped, SkippedFile{Path: source, Reason: "empty rendered name"})
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// Skip directories
 		if info.IsDir() {
+			if relPath == templates.PartialsDir {
+				report.Skipped = append(report.Skipped, SkippedFile{Path: source, Reason: "partials"})
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
 		//Skip template files
 		for _, skipFile := range skipFiles {
 			if info.Name() == skipFile {
-				log.Println("Skipping template file:", skipFile)
+				report.Skipped = append(report.Skipped, SkippedFile{Path: source, Reason: "template metadata"})
 				return nil
 			}
 		}
 
-		// Get the relative path to maintain directory structure
-		relPath, err := filepath.Rel(templatePath, path)
+		content, err := pg.renderContent(path, relPath, info)
 		if err != nil {
-			return fmt.Errorf("failed to get relative path: %w", err)
+			if failFast {
+				return err
+			}
+			report.Errors = append(report.Errors, RenderError{Path: source, Error: err.Error()})
+			return nil
+		}
+
+		mode := info.Mode().Perm()
+		if info.Mode()&os.ModeSymlink != 0 {
+			mode |= os.ModeSymlink
+		}
+		file := RenderedFile{Path: destPath, Content: content, Mode: mode}
+
+		previous, exists := rendered[destPath]
+		switch {
+		case !exists:
+			report.Files = append(report.Files, file)
+		case previous.layer == index:
+			err := fmt.Errorf("%s renders to %s, already produced by %s", source, destPath, previous.path)
+			if failFast {
+				return err
+			}
+			report.Errors = append(report.Errors, RenderError{Path: source, Error: err.Error()})
+			return nil
+		default:
+			// A later layer overrides the file
+			index := slices.IndexFunc(report.Files, func(f RenderedFile) bool { return f.Path == destPath })
+			report.Files[index] = file
+			report.Skipped = append(report.Skipped, SkippedFile{Path: previous.path, Reason: "overridden by " + source})
 		}
+		rendered[destPath] = renderedSource{layer: index, path: source}
+		return nil
+	})
+}
+
+// renderContent returns the content of a template file. Symlinks, binary files and files
+// matching copy_verbatim are copied as they are, everything else is rendered.
+func (pg *ProjectGenerator) renderContent(path, relPath string, info os.FileInfo) ([]byte, error) {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return readTemplateLink(path, relPath)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", relPath, err)
+	}
+	if IsBinary(raw) || pg.template.IsVerbatim(relPath) {
+		return raw, nil
+	}
 
-		// Create the destination path
-		destPath := filepath.Join(pg.outputDir, relPath)
-		if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create directory: %w", err)
+	// Parse and execute the template file, with the shared partials available
+	base := template.New(filepath.Base(path)).Funcs(pg.funcMap)
+	if pg.partials != nil {
+		if base, err = pg.partials.Clone(); err != nil {
+			return nil, fmt.Errorf("error preparing partials for %s: %w", relPath, err)
 		}
+	}
+	tmpl, err := pg.strict(base.New(filepath.Base(path))).Parse(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template file %s: %w", path, err)
+	}
+
+	var content bytes.Buffer
+	if err := tmpl.Execute(&content, pg.context); err != nil {
+		return nil, fmt.Errorf("error executing template for %s: %w", relPath, err)
+	}
+	return content.Bytes(), nil
+}
+
+// loadPartials parses the {{define}} blocks of the repository _partials directory, then of
+// each layer's own _partials directory, so that later definitions win
+func (pg *ProjectGenerator) loadPartials(layers []templates.TemplateLayer) (*template.Template, error) {
+	dirs := []string{filepath.Join(templates.VersionDir(pg.templatesDir, pg.templateVersion), templates.PartialsDir)}
+	for _, layer := range layers {
+		dirs = append(dirs, filepath.Join(templates.VersionDir(pg.templatesDir, pg.templateVersion), layer.Path, templates.PartialsDir))
+	}
 
-		// Parse and execute the template file
-		tmpl, err := template.New(info.Name()).Funcs(pg.funcMap).ParseFiles(path)
+	var partials *template.Template
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if partials == nil {
+				partials = template.New(templates.PartialsDir).Funcs(pg.funcMap)
+			}
+			if _, err := partials.New(path).Parse(string(content)); err != nil {
+				return fmt.Errorf("error parsing partial %s: %w", path, err)
+			}
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("error parsing template file %s: %w", path, err)
+			return nil, err
 		}
+	}
+	return partials, nil
+}
+
+// renderContext returns the data templates are rendered with, from the current variable values
+func (pg *ProjectGenerator) renderContext() *templates.RenderContext {
+	commit, _ := templates.ResolveRevision(pg.templatesDir, pg.templateVersion)
+	name := pg.template.Key
+	if name == "" {
+		name = pg.template.Name
+	}
+	pg.template.ProjectName = pg.projectName
+	return templates.NewRenderContext(pg.template,
+		templates.TemplateContext{Name: name, Version: pg.templateVersion, Commit: commit},
+		templates.MetaContext{JRXVersion: version.Version, GeneratedAt: time.Now().UTC().Truncate(time.Second)})
+}
+
+// strict makes tmpl fail on missing map keys, such as undeclared .Vars, when the template asks for it
+func (pg *ProjectGenerator) strict(tmpl *template.Template) *template.Template {
+	if pg.template.ProjectInfo.Strict {
+		return tmpl.Option("missingkey=error")
+	}
+	return tmpl
+}
 
-		// Create destination file
-		dstFile, err := os.Create(destPath)
+// readTemplateLink returns the target of a symlink inside the template.
+// Only relative links that stay inside the template are allowed, they are recreated as is.
+func readTemplateLink(path, relPath string) ([]byte, error) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading symlink %s: %w", relPath, err)
+	}
+	if filepath.IsAbs(target) || !filepath.IsLocal(filepath.Join(filepath.Dir(relPath), target)) {
+		return nil, fmt.Errorf("symlink %s points outside the template: %s", relPath, target)
+	}
+	return []byte(target), nil
+}
+
+// renderPath executes the template expressions in each segment of a template path.
+// It returns an empty path when a segment renders to an empty name, meaning the
+// file or directory is not part of the project.
+func (pg *ProjectGenerator) renderPath(relPath string) (string, error) {
+	segments := strings.Split(relPath, string(filepath.Separator))
+	for i, segment := range segments {
+		if !strings.Contains(segment, "{{") {
+			continue
+		}
+
+		tmpl, err := pg.strict(template.New(segment).Funcs(pg.funcMap)).Parse(segment)
 		if err != nil {
-			return fmt.Errorf("failed to create destination file: %w", err)
+			return "", err
+		}
+		var name bytes.Buffer
+		if err := tmpl.Execute(&name, pg.context); err != nil {
+			return "", err
 		}
-		defer dstFile.Close()
 
-		// Execute template
-		if err := tmpl.Execute(dstFile, pg.template); err != nil {
-			return fmt.Errorf("error executing template for %s: %w", relPath, err)
+		segments[i] = strings.TrimSpace(name.String())
+		if segments[i] == "" {
+			return "", nil
 		}
+	}
 
-		log.Printf("Rendered: %s\n", relPath)
-		return nil
-	})
+	destPath := filepath.Join(segments...)
+	if !filepath.IsLocal(destPath) {
+		return "", fmt.Errorf("rendered path %s is outside the project", destPath)
+	}
+	return destPath, nil
+}
+
+// validateTemplate checks the project name and that the template exists
+func (pg *ProjectGenerator) validateTemplate() error {
+	if pg.projectName == "" {
+		return errors.NewError("validate project", errors.ErrEmptyProjectName)
+	}
 
+	if pg.template == nil {
+		return errors.NewError("validate project", errors.ErrInvalidTemplate)
+	}
+
+	// Check if the template path of every layer exists
+	for _, layer := range pg.template.GetLayers() {
+		if _, err := os.Stat(filepath.Join(templates.VersionDir(pg.templatesDir, pg.templateVersion), layer.Path)); err != nil {
+			return errors.NewError("validate project", fmt.Errorf("%w: %s", errors.ErrTemplatePathMissing, layer.Path))
+		}
+	}
+
+	return nil
+}
+
+// OutputExists reports whether the output directory is already present
+func (pg *ProjectGenerator) OutputExists() bool {
+	_, err := os.Stat(pg.outputDir)
+	return err == nil
+}
+
+// copyFiles renders the template and writes the files into the output directory
+func (pg *ProjectGenerator) copyFiles() error {
+	files, err := pg.Render()
 	if err != nil {
-		return errors.NewError("copy template files", err)
+		return err
+	}
+
+	for _, file := range files {
+		if err := writeProjectFile(filepath.Join(pg.outputDir, file.Path), file); err != nil {
+			return err
+		}
+		log.Printf("Rendered: %s\n", file.Path)
 	}
 
 	return nil
@@ -182,12 +541,17 @@ func (pg *ProjectGenerator) initializeGit() error {
 	return nil
 }
 
-// createGitHubRepository creates a GitHub repository
-func (pg *ProjectGenerator) CreateGitHubRepository(ctx context.Context, githubOrg string) (*github.Repository, error) {
-	// Import the scm package at the top if not already done
-	ghClient, err := scm.NewGitHubClient(pg.config, githubOrg)
+// CreateAndPush creates the remote repository on the provider and pushes the initial commit.
+// If the push fails, the freshly created repository is deleted again.
+func (pg *ProjectGenerator) CreateAndPush(ctx context.Context, provider scm.Provider, namespace string) (*scm.Repository, error) {
+	log.Printf("Creating %s repository in '%s' and pushing code...\n", provider.Name(), namespace)
+
+	exists, err := provider.RepositoryExists(ctx, namespace, pg.projectName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GitHub client: %w", err)
+		return nil, fmt.Errorf("failed to check repository: %w", err)
+	}
+	if exists {
+		return nil, errors.NewError("create repository", errors.ErrRepositoryExists)
 	}
 
 	// Create repository description from template
@@ -197,47 +561,22 @@ func (pg *ProjectGenerator) CreateGitHubRepository(ctx context.Context, githubOr
 	}
 
 	// Create the repository (private by default)
-	repo, err := ghClient.CreateRepository(ctx, pg.projectName, description, true)
-	if err != nil {
-		return nil, err
-	}
-
-	log.Printf("Repository created: %s\n", repo.GetHTMLURL())
-	return repo, nil
-}
-
-func (pg *ProjectGenerator) CreateAndPushToGitHub(ctx context.Context, githubOrg string) error {
-	log.Println("Creating GitHub repository and pushing code...")
-
-	// Create GitHub repository
-	repo, err := pg.CreateGitHubRepository(ctx, githubOrg)
+	repo, err := provider.CreateRepository(ctx, namespace, pg.projectName, description, true)
 	if err != nil {
-		return fmt.Errorf("failed to create GitHub repository: %w", err)
+		return nil, fmt.Errorf("failed to create repository: %w", err)
 	}
+	log.Printf("Repository created: %s\n", repo.HTMLURL)
 
-	// Add remote and push
-	if err := pg.pushToRemote(repo); err != nil {
-		return fmt.Errorf("failed to push to GitHub: %w", err)
-	}
-
-	log.Printf("✓ Project successfully pushed to: %s\n", repo.GetHTMLURL())
-	return nil
-}
-
-// pushToRemote adds the remote and pushes the code
-func (pg *ProjectGenerator) pushToRemote(repo *github.Repository) error {
-	// Add remote using SSH URL
-	sshURL := repo.GetSSHURL()
-	if err := scm.GitAddRemote(pg.outputDir, "origin", sshURL); err != nil {
-		return err
-	}
-
-	// Push to GitHub
-	if err := scm.GitPush(pg.outputDir, "origin", "main", pg.config.SshKeyPath, pg.config.SshKeyPassphrase); err != nil {
-		return err
+	// Add remote and push, rolling back the repository on failure
+	if err := provider.Push(pg.outputDir, repo); err != nil {
+		if delErr := provider.DeleteRepository(ctx, namespace, pg.projectName); delErr != nil {
+			log.Printf("Warning: could not roll back repository %s/%s: %v\n", namespace, pg.projectName, delErr)
+		}
+		return nil, fmt.Errorf("failed to push to %s: %w", provider.Name(), err)
 	}
 
-	return nil
+	log.Printf("✓ Project successfully pushed to: %s\n", repo.HTMLURL)
+	return repo, nil
 }
 
 // GetOutputDir returns the output directory path
@@ -251,7 +590,7 @@ func (pg *ProjectGenerator) GetProjectName() string {
 }
 
 // CleanupLocalFiles removes the locally created project files
-// This is useful when the project has been pushed to GitHub from a server
+// This is useful when the project has been pushed to a remote from a server
 func (pg *ProjectGenerator) CleanupLocalFiles() error {
 	log.Printf("Cleaning up local files at: %s\n", pg.outputDir)
 	return os.RemoveAll(pg.outputDir)
@@ -0,0 +1,152 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/navigator-systems/jrx/internal/errors"
+	"github.com/navigator-systems/jrx/internal/templates"
+	"github.com/navigator-systems/jrx/internal/version"
+)
+
+// StateDir is the directory inside generated projects where jrx keeps its metadata
+const StateDir = ".jrx"
+
+// StateFile is the name of the provenance manifest inside StateDir
+const StateFile = "project.toml"
+
+// ProjectState records which template, version and variables produced a project
+type ProjectState struct {
+	Project   StateProject      `toml:"project"`
+	Template  StateTemplate     `toml:"template"`
+	Variables map[string]string `toml:"variables"`
+	Generator StateGenerator    `toml:"generator"`
+}
+
+// StateProject describes the generated project
+type StateProject struct {
+	Name string `toml:"name"`
+}
+
+// StateTemplate describes the template the project was rendered from
+type StateTemplate struct {
+	Name    string `toml:"name"`             // Key of the template in templates.toml
+	Version string `toml:"version"`          // Branch or tag of the templates repository
+	Commit  string `toml:"commit,omitempty"` // Commit SHA the version resolved to
+	Repo    string `toml:"repo"`             // Templates repository URL
+	Source  string `toml:"source,omitempty"` // Template source in .jrxrc, empty for templates_repo
+}
+
+// StateGenerator describes the jrx run that generated the project
+type StateGenerator struct {
+	JRXVersion  string     `toml:"jrx_version"`
+	GeneratedAt time.Time  `toml:"generated_at"`
+	UpgradedAt  *time.Time `toml:"upgraded_at,omitempty"` // Last 'jrx project upgrade'
+}
+
+// buildState collects the provenance of the project being generated
+func (pg *ProjectGenerator) buildState() ProjectState {
+	commit, err := templates.ResolveRevision(pg.templatesDir, pg.templateVersion)
+	if err != nil {
+		log.Printf("Warning: could not resolve template revision: %v\n", err)
+	} else if commit == "" {
+		log.Printf("Local templates have uncommitted changes, no template commit is recorded\n")
+	}
+
+	name := pg.template.Key
+	if name == "" {
+		name = pg.template.Name
+	}
+
+	repo := pg.config.TemplatesRepo
+	if pg.templateVersion == templates.LocalVersion {
+		if repo, err = filepath.Abs(pg.templatesDir); err != nil {
+			repo = pg.templatesDir
+		}
+	}
+
+	variables := make(map[string]string, len(pg.template.Variables))
+	for _, variable := range pg.template.Variables {
+		variables[variable.Key] = variable.Default
+	}
+
+	return ProjectState{
+		Project: StateProject{Name: pg.projectName},
+		Template: StateTemplate{
+			Name:    name,
+			Version: pg.templateVersion,
+			Commit:  commit,
+			Repo:    repo,
+			Source:  pg.template.Source,
+		},
+		Variables: variables,
+		Generator: StateGenerator{
+			JRXVersion:  version.Version,
+			GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		},
+	}
+}
+
+// UpgradedState returns the project state after upgrading to the generator's template version
+func (pg *ProjectGenerator) UpgradedState(previous ProjectState) ProjectState {
+	state := pg.buildState()
+	now := state.Generator.GeneratedAt
+	state.Generator.GeneratedAt = previous.Generator.GeneratedAt
+	state.Generator.UpgradedAt = &now
+	return state
+}
+
+// writeState writes the provenance manifest into the project
+func (pg *ProjectGenerator) writeState() error {
+	if err := WriteProjectState(pg.outputDir, pg.buildState()); err != nil {
+		return errors.NewError("write project state", err)
+	}
+	log.Printf("Rendered: %s\n", filepath.Join(StateDir, StateFile))
+	return nil
+}
+
+// WriteProjectState writes state to <projectDir>/.jrx/project.toml
+func WriteProjectState(projectDir string, state ProjectState) error {
+	stateDir := filepath.Join(projectDir, StateDir)
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", stateDir, err)
+	}
+
+	content, err := EncodeProjectState(state)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(stateDir, StateFile), content, 0644); err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	return nil
+}
+
+// EncodeProjectState returns the TOML content of the state file
+func EncodeProjectState(state ProjectState) ([]byte, error) {
+	var content bytes.Buffer
+	fmt.Fprintln(&content, "# Generated by jrx. Used by 'jrx project upgrade' and 'jrx project diff'.")
+	if err := toml.NewEncoder(&content).Encode(state); err != nil {
+		return nil, err
+	}
+	return content.Bytes(), nil
+}
+
+// ReadProjectState reads <projectDir>/.jrx/project.toml
+func ReadProjectState(projectDir string) (ProjectState, error) {
+	var state ProjectState
+	statePath := filepath.Join(projectDir, StateDir, StateFile)
+	if _, err := toml.DecodeFile(statePath, &state); err != nil {
+		if os.IsNotExist(err) {
+			return state, errors.NewError("read project state", errors.ErrStateNotFound)
+		}
+		return state, errors.NewError("read project state", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,149 @@
+package generator
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/navigator-systems/jrx/internal/diff"
+	"github.com/navigator-systems/jrx/internal/errors"
+)
+
+// UpgradeResult summarizes the changes applied to a project by an upgrade
+type UpgradeResult struct {
+	Added     []string // Files introduced by the new template version
+	Updated   []string // Files merged without conflicts
+	Removed   []string // Files dropped by the template and unchanged locally
+	Conflicts []string // Files left with conflict markers (or a .jrx-upgrade copy for binaries)
+	Skipped   []string // Files changed by the template but deleted or diverged locally
+}
+
+// ApplyUpgrade three-way merges the differences between two renders of a template into projectDir.
+// base is the render of the recorded template version, target the render of the new version;
+// the files in projectDir are the local side of the merge.
+func ApplyUpgrade(projectDir string, base, target []RenderedFile, targetLabel string) (UpgradeResult, error) {
+	var result UpgradeResult
+
+	baseFiles := indexFiles(base)
+	targetFiles := indexFiles(target)
+
+	paths := make([]string, 0, len(baseFiles)+len(targetFiles))
+	for path := range baseFiles {
+		paths = append(paths, path)
+	}
+	for path := range targetFiles {
+		if _, ok := baseFiles[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		baseFile, inBase := baseFiles[path]
+		targetFile, inTarget := targetFiles[path]
+
+		// The template did not change this file, nothing to do
+		if inBase && inTarget && bytes.Equal(baseFile.Content, targetFile.Content) && baseFile.Mode == targetFile.Mode {
+			continue
+		}
+
+		localPath := filepath.Join(projectDir, path)
+		local, err := ReadProjectFile(localPath)
+		localExists := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return result, errors.NewError("read project file", err)
+		}
+
+		// Existing files keep the mode the user gave them, unless only the template changed it
+		var localMode os.FileMode
+		if localExists && inTarget {
+			info, err := os.Lstat(localPath)
+			if err != nil {
+				return result, errors.NewError("read project file", err)
+			}
+			targetFile.Mode = upgradeMode(info, baseFile, inBase, targetFile)
+			localMode = info.Mode()
+		}
+
+		switch {
+		case !inTarget:
+			// Removed from the template: only delete it if it was not modified locally
+			if !localExists {
+				continue
+			}
+			if bytes.Equal(local, baseFile.Content) {
+				if err := os.Remove(localPath); err != nil {
+					return result, errors.NewError("remove project file", err)
+				}
+				result.Removed = append(result.Removed, path)
+			} else {
+				result.Skipped = append(result.Skipped, path)
+			}
+
+		case !localExists:
+			// New in the template, or deleted locally on purpose
+			if inBase {
+				result.Skipped = append(result.Skipped, path)
+				continue
+			}
+			if err := writeProjectFile(localPath, targetFile); err != nil {
+				return result, err
+			}
+			result.Added = append(result.Added, path)
+
+		case bytes.Equal(local, targetFile.Content):
+			// Already matches the new version, apart from a mode the template changed
+			if localMode.IsRegular() && !targetFile.IsSymlink() && localMode.Perm() != targetFile.Mode.Perm() {
+				if err := os.Chmod(localPath, targetFile.Mode.Perm()); err != nil {
+					return result, errors.NewError("write project file", err)
+				}
+				result.Updated = append(result.Updated, path)
+			}
+			continue
+
+		case inBase && bytes.Equal(local, baseFile.Content):
+			// Not modified locally, take the new version as is
+			if err := writeProjectFile(localPath, targetFile); err != nil {
+				return result, err
+			}
+			result.Updated = append(result.Updated, path)
+
+		case IsBinary(local) || IsBinary(targetFile.Content) || targetFile.IsSymlink():
+			// Binary content and links can't be merged, leave the new version next to the local one
+			if err := writeProjectFile(localPath+".jrx-upgrade", targetFile); err != nil {
+				return result, err
+			}
+			result.Conflicts = append(result.Conflicts, path)
+
+		default:
+			var baseLines []string
+			if inBase {
+				baseLines = diff.Lines(string(baseFile.Content))
+			}
+			merged := diff.Merge3(baseLines, diff.Lines(string(local)), diff.Lines(string(targetFile.Content)), "local", targetLabel)
+			if err := writeProjectFile(localPath, RenderedFile{Path: path, Content: []byte(merged.Text()), Mode: targetFile.Mode}); err != nil {
+				return result, err
+			}
+			if merged.Conflicts > 0 {
+				result.Conflicts = append(result.Conflicts, path)
+			} else {
+				result.Updated = append(result.Updated, path)
+			}
+		}
+	}
+
+	log.Printf("Upgrade applied: %d added, %d updated, %d removed, %d conflicts\n",
+		len(result.Added), len(result.Updated), len(result.Removed), len(result.Conflicts))
+	return result, nil
+}
+
+// indexFiles maps rendered files by their relative path
+func indexFiles(files []RenderedFile) map[string]RenderedFile {
+	index := make(map[string]RenderedFile, len(files))
+	for _, file := range files {
+		index[file.Path] = file
+	}
+	return index
+}
@@ -2,15 +2,21 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/navigator-systems/jrx/internal/adapters/scm"
 	"github.com/navigator-systems/jrx/internal/generator"
 	"github.com/navigator-systems/jrx/internal/templates"
+	"github.com/navigator-systems/jrx/internal/vars"
 )
 
 // handleIndex handles the home page request
@@ -39,22 +45,32 @@ func (s *Server) handleTemplates(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tm, source, err := s.templateManager(strings.TrimSpace(r.URL.Query().Get("source")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
 	data := struct {
 		Title     string
 		IsLoaded  bool
 		Templates []templates.RootTemplate
+		Sources   []string
+		Source    string
 		Versions  []string
 		Current   string
 		Error     string
 	}{
 		Title:    "JRX Templates",
-		IsLoaded: s.templateManager.IsLoaded(),
-		Versions: s.templateManager.GetAvailableVersions(),
-		Current:  s.templateManager.GetCurrentVersion(),
+		IsLoaded: tm.IsLoaded(),
+		Sources:  s.sources.Names(),
+		Source:   source,
+		Versions: tm.GetAvailableVersions(),
+		Current:  tm.GetCurrentVersion(),
 	}
 
-	if s.templateManager.IsLoaded() {
-		tmplList, err := s.templateManager.ListAll()
+	if tm.IsLoaded() {
+		tmplList, err := tm.ListAll()
 		if err != nil {
 			data.Error = err.Error()
 		} else {
@@ -74,20 +90,26 @@ func (s *Server) handleDownloadTemplates(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	tm, source, err := s.templateManager(strings.TrimSpace(r.FormValue("source")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
 	// Initialize (download) templates
-	if err := s.templateManager.Initialize(); err != nil {
+	if err := tm.Initialize(); err != nil {
 		http.Error(w, fmt.Sprintf("Error downloading templates: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Reload templates
 	selectedVersion := strings.TrimSpace(r.FormValue("version"))
-	if err := s.templateManager.LoadTemplates(selectedVersion); err != nil {
+	if err := tm.LoadTemplates(selectedVersion); err != nil {
 		http.Error(w, fmt.Sprintf("Error loading templates: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	http.Redirect(w, r, "/templates", http.StatusSeeOther)
+	http.Redirect(w, r, "/templates?source="+url.QueryEscape(source), http.StatusSeeOther)
 }
 
 // handleNewProject displays the new project page
@@ -103,36 +125,47 @@ func (s *Server) handleNewProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tm, source, err := s.templateManager(strings.TrimSpace(r.URL.Query().Get("source")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defaultVersion := tm.Config().TemplatesDefault
+
 	selectedVersion := strings.TrimSpace(r.URL.Query().Get("templateVersion"))
 	if selectedVersion == "" {
-		selectedVersion = s.config.TemplatesDefault
+		selectedVersion = defaultVersion
 	}
 
 	data := struct {
 		Title         string
-		Templates     map[string]templates.RootTemplate
-		Organizations []string
+		Templates     map[string]templates.RootTemplate // Keyed by template address, <source>/<key>
+		Targets       []scm.Target
+		Sources       []string
+		Source        string
 		Versions      []string
 		Current       string
 		VersionCounts map[string]int
 		Error         string
 	}{
 		Title:         "Create New Project",
-		Organizations: s.config.GitProvider.GithubOrganization,
-		Versions:      s.templateManager.GetAvailableVersions(),
+		Targets:       s.providers.Targets(),
+		Sources:       s.sources.Names(),
+		Source:        source,
+		Versions:      tm.GetAvailableVersions(),
 		Current:       selectedVersion,
 		VersionCounts: map[string]int{},
 	}
 
-	if !s.templateManager.ValidateVersion(selectedVersion) {
+	if !tm.ValidateVersion(selectedVersion) {
 		data.Error = fmt.Sprintf("Template version '%s' is not available", selectedVersion)
-		selectedVersion = s.config.TemplatesDefault
+		selectedVersion = defaultVersion
 		data.Current = selectedVersion
 	}
 
 	data.VersionCounts = map[string]int{}
 	for _, version := range data.Versions {
-		data.VersionCounts[version] = s.templateManager.GetVersionCount(version)
+		data.VersionCounts[version] = tm.GetVersionCount(version)
 	}
 	/*
 		if len(data.Versions) > 0 {
@@ -152,10 +185,13 @@ func (s *Server) handleNewProject(w http.ResponseWriter, r *http.Request) {
 		}
 	*/
 
-	if err := s.templateManager.LoadTemplates(selectedVersion); err != nil {
+	if err := tm.LoadTemplates(selectedVersion); err != nil {
 		data.Error = err.Error()
 	} else {
-		data.Templates = s.templateManager.GetTemplatesMap()
+		data.Templates = map[string]templates.RootTemplate{}
+		for key, tpl := range tm.GetTemplatesMap() {
+			data.Templates[templates.QualifiedName(source, key)] = tpl
+		}
 	}
 
 	if err := tmpl.Execute(w, data); err != nil {
@@ -163,42 +199,42 @@ func (s *Server) handleNewProject(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// projectResult is the data rendered by project-result.html
+type projectResult struct {
+	Title           string
+	ProjectName     string
+	TemplateName    string
+	TemplateVersion string
+	Variables       map[string]string
+	Success         bool
+	Message         string
+	OutputDir       string
+	Provider        string
+	Namespace       string
+	RepoURL         string
+	Hooks           []generator.HookResult
+}
+
 // handleCreateProject handles the project creation form submission
 func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 	projectName := strings.TrimSpace(r.FormValue("projectName"))
 	templateName := strings.TrimSpace(r.FormValue("templateName"))
 	templateVersion := strings.TrimSpace(r.FormValue("templateVersion"))
-	githubOrg := strings.TrimSpace(r.FormValue("githubOrg"))
+
+	// The remote target is submitted as "provider:namespace"
+	providerName, namespace, _ := strings.Cut(strings.TrimSpace(r.FormValue("target")), ":")
 
 	// Parse variables from form fields (var_keyname)
-	vars := make(map[string]string)
-	if err := r.ParseForm(); err == nil {
-		for key, values := range r.Form {
-			if strings.HasPrefix(key, "var_") && len(values) > 0 {
-				varName := strings.TrimPrefix(key, "var_")
-				vars[varName] = values[0]
-			}
-		}
-	}
+	userVars := vars.FromForm(r.Form)
 
-	data := struct {
-		Title           string
-		ProjectName     string
-		TemplateName    string
-		TemplateVersion string
-		Variables       map[string]string
-		Success         bool
-		Message         string
-		OutputDir       string
-		GithubOrg       string
-		GithubRepoURL   string
-	}{
+	data := projectResult{
 		Title:           "Project Creation Result",
 		ProjectName:     projectName,
 		TemplateName:    templateName,
 		TemplateVersion: templateVersion,
-		Variables:       vars,
-		GithubOrg:       githubOrg,
+		Variables:       userVars,
+		Provider:        providerName,
+		Namespace:       namespace,
 		Success:         true,
 		Message:         "Project created successfully!",
 	}
@@ -212,11 +248,11 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 		data.Message = "Error: Template name is required"
 	} else {
 		// Create the project
-		if err := s.createProject(projectName, templateName, templateVersion, vars, githubOrg, &data, w, r); err != nil {
+		if err := s.createProject(projectName, templateName, templateVersion, userVars, providerName, namespace, &data, w, r); err != nil {
 			data.Success = false
 			data.Message = fmt.Sprintf("Error: %v", err)
-		} else if githubOrg == "" {
-			// If no GitHub org, the project was downloaded as ZIP
+		} else if providerName == "" {
+			// If no remote was selected, the project was downloaded as ZIP
 			// Response already sent, so return early
 			return
 		}
@@ -258,72 +294,40 @@ func (s *Server) handleGithubOrgs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// createProject creates a project from template with optional GitHub push
-func (s *Server) createProject(projectName, templateName, templateVersion string, vars map[string]string, githubOrg string, data *struct {
-	Title           string
-	ProjectName     string
-	TemplateName    string
-	TemplateVersion string
-	Variables       map[string]string
-	Success         bool
-	Message         string
-	OutputDir       string
-	GithubOrg       string
-	GithubRepoURL   string
-}, w http.ResponseWriter, r *http.Request) error {
+// createProject creates a project from template with an optional push to a provider
+func (s *Server) createProject(projectName, templateName, templateVersion string, vars map[string]string, providerName, namespace string, data *projectResult, w http.ResponseWriter, r *http.Request) error {
 	// Log for debugging
-	log.Printf("Creating project: name=%s, template=%s, version=%s, org=%s\n", projectName, templateName, templateVersion, githubOrg)
-
-	// Verify templates are loaded
-	if !s.templateManager.IsLoaded() {
-		return fmt.Errorf("templates are not loaded, please wait for server initialization")
-	}
-
-	if templateVersion == "" {
-		templateVersion = s.config.TemplatesDefault
-	}
-	if !s.templateManager.ValidateVersion(templateVersion) {
-		return fmt.Errorf("template version '%s' is not available", templateVersion)
-	}
-	if err := s.templateManager.LoadTemplates(templateVersion); err != nil {
-		return fmt.Errorf("failed to load templates for version '%s': %w", templateVersion, err)
-	}
-
-	// Get the specific template
-	tmpl, err := s.templateManager.GetTemplate(templateName)
-	if err != nil {
-		// Log available templates for debugging
-		availableTemplates, _ := s.templateManager.ListAll()
-		log.Printf("Template '%s' not found. Available templates: ", templateName)
-		for _, t := range availableTemplates {
-			log.Printf("  - %s\n", t.Name)
+	log.Printf("Creating project: name=%s, template=%s, version=%s, provider=%s, namespace=%s\n", projectName, templateName, templateVersion, providerName, namespace)
+
+	// Resolve the provider before generating anything
+	var provider scm.Provider
+	if providerName != "" {
+		var err error
+		if provider, err = s.providers.Get(providerName); err != nil {
+			return err
+		}
+		if !slices.Contains(provider.Namespaces(), namespace) {
+			return fmt.Errorf("namespace '%s' is not configured for provider '%s'", namespace, providerName)
 		}
-		return fmt.Errorf("template '%s' not found", templateName)
 	}
 
-	// Apply user variables to template
-	if len(vars) > 0 {
-		for i := range tmpl.Variables {
-			if userValue, exists := vars[tmpl.Variables[i].Key]; exists {
-				tmpl.Variables[i].Default = userValue
-				log.Printf("Variable '%s' set to: %s\n", tmpl.Variables[i].Key, userValue)
-			}
-		}
+	pg, err := s.prepareProject(projectName, templateName, templateVersion, vars)
+	if err != nil {
+		return err
 	}
-	version := templateVersion
-	// Create project generator
-	pg := generator.NewProjectGenerator(tmpl, projectName, s.templateManager.GetTemplatesDir(), version, s.templateManager.GetFuncMap(), s.config)
 
 	// Generate the project
-	if err := pg.Generate(); err != nil {
+	err = pg.Generate()
+	data.Hooks = pg.HookResults()
+	if err != nil {
 		return fmt.Errorf("failed to generate project: %w", err)
 	}
 
 	data.OutputDir = pg.GetOutputDir()
 	data.Message = fmt.Sprintf("Project '%s' created successfully at: %s", projectName, pg.GetOutputDir())
 
-	// If no GitHub organization, create ZIP and serve as download
-	if githubOrg == "" {
+	// If no remote was selected, create ZIP and serve as download
+	if provider == nil {
 		zipPath := pg.GetOutputDir() + ".zip"
 		if err := s.createZipArchive(pg.GetOutputDir(), zipPath); err != nil {
 			return fmt.Errorf("failed to create zip archive: %w", err)
@@ -349,28 +353,178 @@ func (s *Server) createProject(projectName, templateName, templateVersion string
 		return nil
 	}
 
-	// If GitHub organization is specified, create repo and push
-	if githubOrg != "" {
-		ctx := context.Background()
-		if err := pg.CreateAndPushToGitHub(ctx, githubOrg); err != nil {
-			data.Message = fmt.Sprintf("Project created locally at: %s\nWarning: Failed to push to GitHub: %v", pg.GetOutputDir(), err)
-			log.Printf("Failed to create/push GitHub repository: %v\n", err)
+	// Otherwise create the remote repository and push
+	ctx := context.Background()
+	repo, err := pg.CreateAndPush(ctx, provider, namespace)
+	if err != nil {
+		data.Message = fmt.Sprintf("Project created locally at: %s\nWarning: Failed to push to %s: %v", pg.GetOutputDir(), providerName, err)
+		log.Printf("Failed to create/push repository: %v\n", err)
+	} else {
+		data.RepoURL = repo.HTMLURL
+
+		// Clean up local files since project is now on the remote
+		if err := pg.CleanupLocalFiles(); err != nil {
+			log.Printf("Warning: Failed to cleanup local files: %v\n", err)
+			data.Message = fmt.Sprintf("Project '%s' created and pushed to %s successfully!\nRepository: %s\nWarning: Could not cleanup local files at: %s", projectName, providerName, repo.HTMLURL, pg.GetOutputDir())
 		} else {
-			// Get the GitHub URL (we need to construct it from the org and project name)
-			githubURL := fmt.Sprintf("%s/%s/%s", s.config.GitProvider.GithubURL, githubOrg, projectName)
-			data.GithubRepoURL = githubURL
-
-			// Clean up local files since project is now on GitHub
-			if err := pg.CleanupLocalFiles(); err != nil {
-				log.Printf("Warning: Failed to cleanup local files: %v\n", err)
-				data.Message = fmt.Sprintf("Project '%s' created and pushed to GitHub successfully!\nRepository: %s\nWarning: Could not cleanup local files at: %s", projectName, githubURL, pg.GetOutputDir())
-			} else {
-				data.OutputDir = "" // Clear output dir since files were cleaned up
-				data.Message = fmt.Sprintf("Project '%s' created and pushed to GitHub successfully!\nRepository: %s\nLocal files have been cleaned up.", projectName, githubURL)
-			}
+			data.OutputDir = "" // Clear output dir since files were cleaned up
+			data.Message = fmt.Sprintf("Project '%s' created and pushed to %s successfully!\nRepository: %s\nLocal files have been cleaned up.", projectName, providerName, repo.HTMLURL)
 		}
 	}
 
 	log.Printf("Project '%s' created successfully from template '%s'\n", projectName, templateName)
 	return nil
 }
+
+// prepareProject loads the requested template version and returns a generator with the user variables applied
+func (s *Server) prepareProject(projectName, templateName, templateVersion string, vars map[string]string) (*generator.ProjectGenerator, error) {
+	// Templates of [template_sources] are addressed as <source>/<template>
+	tm, templateKey, err := s.sources.Resolve(templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify templates are loaded
+	if !tm.IsLoaded() {
+		return nil, fmt.Errorf("templates are not loaded, please wait for server initialization")
+	}
+
+	templateVersion, err = tm.ResolveVersion(templateVersion)
+	if err != nil {
+		return nil, err
+	}
+	if !tm.ValidateVersion(templateVersion) {
+		return nil, fmt.Errorf("template version '%s' is not available", templateVersion)
+	}
+	if err := tm.LoadTemplates(templateVersion); err != nil {
+		return nil, fmt.Errorf("failed to load templates for version '%s': %w", templateVersion, err)
+	}
+
+	// Get the specific template
+	tmpl, err := tm.GetTemplate(templateKey)
+	if err != nil {
+		// Log available templates for debugging
+		availableTemplates, _ := tm.ListAll()
+		log.Printf("Template '%s' not found. Available templates: ", templateName)
+		for _, t := range availableTemplates {
+			log.Printf("  - %s\n", t.Name)
+		}
+		return nil, fmt.Errorf("template '%s' not found", templateName)
+	}
+
+	// The form submits every field, computed ones left empty are not explicit values
+	for _, variable := range tmpl.Variables {
+		if variable.Expr != "" && vars[variable.Key] == "" {
+			delete(vars, variable.Key)
+		}
+	}
+
+	// Apply and validate the user variables before anything is written
+	tmpl.ProjectName = projectName
+	if err := tmpl.ApplyVariables(vars); err != nil {
+		return nil, err
+	}
+
+	// Create project generator
+	pg := generator.NewProjectGenerator(tmpl, projectName, tm.GetTemplatesDir(), templateVersion, tm.GetFuncMap(), tm.Config())
+	pg.SetHookPolicy(s.hookPolicy())
+	return pg, nil
+}
+
+// hookPolicy returns the template hooks the server runs, from server_hooks in .jrxrc
+func (s *Server) hookPolicy() generator.HookPolicy {
+	switch s.config.ServerHooks {
+	case "none":
+		return generator.HookPolicy{}
+	case "all":
+		return generator.HookPolicy{Commands: true, Actions: true}
+	default:
+		return generator.HookPolicy{Actions: true}
+	}
+}
+
+// previewResponse is the JSON returned by the preview endpoint
+type previewResponse struct {
+	ProjectName     string                  `json:"projectName"`
+	TemplateName    string                  `json:"templateName"`
+	TemplateVersion string                  `json:"templateVersion"`
+	Files           []previewFile           `json:"files"`
+	Skipped         []generator.SkippedFile `json:"skipped"`
+	Errors          []generator.RenderError `json:"errors"`
+	Hooks           []generator.HookResult  `json:"hooks"`
+	Content         *string                 `json:"content,omitempty"` // Rendered content of the requested path
+	Error           string                  `json:"error,omitempty"`
+}
+
+// previewFile is a file that would be created
+type previewFile struct {
+	Path   string `json:"path"`
+	Size   int    `json:"size"`
+	Mode   string `json:"mode"`
+	Binary bool   `json:"binary,omitempty"`
+	Link   string `json:"link,omitempty"` // Target of a symlink
+}
+
+// handlePreviewProject renders the submitted project form in memory and returns what would be created
+func (s *Server) handlePreviewProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projectName := strings.TrimSpace(r.FormValue("projectName"))
+	templateName := strings.TrimSpace(r.FormValue("templateName"))
+	templateVersion := strings.TrimSpace(r.FormValue("templateVersion"))
+	showPath := strings.TrimSpace(r.FormValue("path"))
+
+	resp := previewResponse{
+		ProjectName:     projectName,
+		TemplateName:    templateName,
+		TemplateVersion: templateVersion,
+	}
+	status := http.StatusOK
+
+	if projectName == "" || templateName == "" {
+		resp.Error = "project name and template name are required"
+		status = http.StatusBadRequest
+	} else if pg, err := s.prepareProject(projectName, templateName, templateVersion, vars.FromForm(r.Form)); err != nil {
+		resp.Error = err.Error()
+		status = http.StatusBadRequest
+	} else if report, err := pg.Preview(); err != nil {
+		resp.Error = err.Error()
+		status = http.StatusUnprocessableEntity
+	} else {
+		resp.Skipped = report.Skipped
+		resp.Errors = report.Errors
+		resp.Hooks = report.Hooks
+		found := false
+		for _, file := range report.Files {
+			preview := previewFile{Path: file.Path, Size: len(file.Content), Mode: file.Mode.String()}
+			if file.IsSymlink() {
+				preview.Size, preview.Link = 0, string(file.Content)
+			} else {
+				preview.Binary = generator.IsBinary(file.Content)
+			}
+			resp.Files = append(resp.Files, preview)
+
+			if showPath != "" && file.Path == filepath.Clean(showPath) {
+				found = true
+				// Binary content is not returned, only described
+				if !preview.Binary {
+					content := string(file.Content)
+					resp.Content = &content
+				}
+			}
+		}
+		if showPath != "" && !found {
+			resp.Error = fmt.Sprintf("'%s' is not generated by this template", showPath)
+			status = http.StatusNotFound
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding preview response: %v\n", err)
+	}
+}
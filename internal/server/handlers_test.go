@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/navigator-systems/jrx/internal/config"
+)
+
+// newTestServer returns a server reading templates from a local directory holding files
+func newTestServer(t *testing.T, files map[string]string) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewServer(config.JRXConfig{TemplatesDir: dir})
+	tm, _, err := s.templateManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.LoadTemplates(""); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestPreviewProjectComputesEmptyFormFields(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"templates.toml": "[templates.svc]\nname = \"svc\"\npath = \"svc\"\n",
+		"svc/vars.toml": `
+[variable.org]
+required = true
+
+[variable.module]
+default = "example.com/{{ .org }}/{{ .ProjectName }}"
+required = true
+`,
+		"svc/go.mod": "module {{ .Vars.module }}\n",
+	})
+
+	tests := []struct {
+		name   string
+		module string
+		want   string
+	}{
+		{name: "empty computed field", module: "", want: "module example.com/acme/app\n"},
+		{name: "filled computed field", module: "example.com/custom", want: "module example.com/custom\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{
+				"projectName":  {"app"},
+				"templateName": {"svc"},
+				"path":         {"go.mod"},
+				"var_org":      {"acme"},
+				"var_module":   {tt.module},
+			}
+			req := httptest.NewRequest(http.MethodPost, "/project/preview", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+
+			s.handlePreviewProject(rec, req)
+
+			var resp previewResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d, error %q", rec.Code, resp.Error)
+			}
+			if resp.Content == nil || *resp.Content != tt.want {
+				t.Errorf("go.mod = %v, want %q", resp.Content, tt.want)
+			}
+		})
+	}
+}
@@ -1,47 +1,76 @@
 package server
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 
+	"github.com/navigator-systems/jrx/internal/adapters/scm"
 	"github.com/navigator-systems/jrx/internal/config"
 	"github.com/navigator-systems/jrx/internal/templates"
 )
 
 // Server represents the web server
 type Server struct {
-	config          config.JRXConfig
-	templateManager *templates.TemplateManager
-	port            string
-	currentVersion  string
+	config         config.JRXConfig
+	sources        *templates.Sources
+	providers      *scm.Registry
+	port           string
+	currentVersion string
 }
 
 // NewServer creates a new server instance
 func NewServer(cfg config.JRXConfig) *Server {
+	providers, err := scm.NewRegistry(cfg)
+	if err != nil {
+		log.Printf("Warning: Could not load all SCM providers: %v\n", err)
+	}
+
+	sources, err := templates.NewSources(cfg)
+	if err != nil {
+		log.Printf("Warning: Could not load all template sources: %v\n", err)
+	}
+
 	return &Server{
-		config:          cfg,
-		templateManager: templates.NewTemplateManager(cfg),
-		port:            cfg.ServerPort,
-		currentVersion:  cfg.TemplatesDefault,
+		config:         cfg,
+		sources:        sources,
+		providers:      providers,
+		port:           cfg.ServerPort,
+		currentVersion: cfg.TemplatesDefault,
 	}
 }
 
+// templateManager returns the template manager of a source, the first source if none is given
+func (s *Server) templateManager(source string) (*templates.TemplateManager, string, error) {
+	if source == "" && len(s.sources.Names()) > 0 {
+		source = s.sources.Names()[0]
+	}
+	tm, err := s.sources.Get(source)
+	return tm, source, err
+}
+
 // Start initializes and starts the web server
 func (s *Server) Start() error {
 	// Download/Initialize templates on server start
 	log.Println("Initializing templates...")
-	if err := s.templateManager.Initialize(); err != nil {
+	if err := s.sources.Initialize(); err != nil {
 		log.Printf("Warning: Could not initialize templates: %v\n", err)
 	} else {
 		log.Println("Templates initialized successfully")
 	}
 
-	defaultVersion := s.config.TemplatesDefault
-	// Load templates
-	if err := s.templateManager.LoadTemplates(defaultVersion); err != nil {
-		log.Printf("Warning: Could not load templates: %v\n", err)
-	} else {
-		log.Println("Templates loaded successfully")
+	// Load the default version of every source
+	for _, name := range s.sources.Names() {
+		tm, _ := s.sources.Get(name)
+		label := "templates"
+		if name != "" {
+			label = fmt.Sprintf("templates of source '%s'", name)
+		}
+		if err := tm.LoadTemplates(""); err != nil {
+			log.Printf("Warning: Could not load %s: %v\n", label, err)
+		} else {
+			log.Printf("Loaded %s successfully\n", label)
+		}
 	}
 
 	// Create a new ServeMux to properly handle routes
@@ -57,6 +86,7 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/templates", s.handleTemplates)
 
 	mux.HandleFunc("/github-orgs", s.handleGithubOrgs)
+	mux.HandleFunc("/project/preview", s.handlePreviewProject)
 	mux.HandleFunc("/project", s.handleNewProject) // New project creation
 
 	mux.HandleFunc("/", s.handleIndex)
@@ -0,0 +1,361 @@
+package templates
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/navigator-systems/jrx/internal/errors"
+)
+
+// mirrorDir holds the bare mirror of the templates repository inside the cache directory
+const mirrorDir = ".mirror"
+
+// versionsDir holds the trees of the cached versions by commit, <cache>/<version> links to one
+const versionsDir = ".versions"
+
+// stagingDir holds trees while they are being written and validated
+const stagingDir = ".staging"
+
+// RevisionFile records, inside a version directory, the commit it was materialized from
+const RevisionFile = ".jrx-revision"
+
+// mirrorRefSpecs mirror every branch and tag of the templates repository
+var mirrorRefSpecs = []gitconfig.RefSpec{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+// openMirror opens the bare mirror of the templates repository, creating it on first use.
+// The origin remote follows templates_repo if it changed since the mirror was created.
+func (tm *TemplateManager) openMirror() (*git.Repository, error) {
+	path := filepath.Join(tm.config.TemplatesCacheDir, mirrorDir)
+	repo, err := git.PlainOpen(path)
+	if err == git.ErrRepositoryNotExists {
+		repo, err = git.PlainInit(path, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := repo.Remote("origin")
+	if err == nil && slices.Equal(remote.Config().URLs, []string{tm.config.TemplatesRepo}) {
+		return repo, nil
+	}
+	if err == nil {
+		if err := repo.DeleteRemote("origin"); err != nil {
+			return nil, err
+		}
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{
+		Name:  "origin",
+		URLs:  []string{tm.config.TemplatesRepo},
+		Fetch: mirrorRefSpecs,
+	})
+	return repo, err
+}
+
+// fetchMirror fetches the new objects and the moved branches and tags into the mirror
+func fetchMirror(repo *git.Repository, auth transport.AuthMethod) error {
+	err := repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   mirrorRefSpecs,
+		Auth:       auth,
+		Force:      true,
+		Prune:      true,
+		Tags:       git.NoTags,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	return nil
+}
+
+// resolveVersion returns the commit a branch or tag of the mirror points to
+func resolveVersion(repo *git.Repository, ref plumbing.ReferenceName) (plumbing.Hash, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref.String()))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("%s not found in the templates repository: %w", ref.Short(), err)
+	}
+	return *hash, nil
+}
+
+// syncVersion points <cache>/<version> at the tree of commit.
+// The tree is written and validated in a staging directory first, then the version link is
+// swapped atomically, so a failed update keeps the previous templates of the version.
+func (tm *TemplateManager) syncVersion(repo *git.Repository, version string, commit plumbing.Hash) error {
+	versionLink := filepath.Join(tm.config.TemplatesCacheDir, version)
+	if readRevision(versionLink) == commit.String() {
+		log.Printf("Templates '%s' up to date at %s\n", version, commit.String()[:7])
+		return nil
+	}
+
+	tree := filepath.Join(tm.config.TemplatesCacheDir, versionsDir, commit.String())
+	if _, err := os.Stat(tree); err != nil {
+		staging := filepath.Join(tm.config.TemplatesCacheDir, stagingDir, commit.String())
+		if err := os.RemoveAll(staging); err != nil {
+			return err
+		}
+		if err := materialize(repo, commit, staging); err != nil {
+			os.RemoveAll(staging)
+			return err
+		}
+		if err := validateTree(staging); err != nil {
+			os.RemoveAll(staging)
+			return fmt.Errorf("templates at %s are invalid, keeping the cached version: %w", commit.String()[:7], err)
+		}
+		if err := os.MkdirAll(filepath.Dir(tree), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(staging, tree); err != nil {
+			return err
+		}
+	}
+
+	if err := swapLink(versionLink, tree); err != nil {
+		return err
+	}
+	delete(tm.cache, version)
+	log.Printf("Templates '%s' updated to %s\n", version, commit.String()[:7])
+	return nil
+}
+
+// validateTree checks that the templates.toml of a materialized tree decodes and that every
+// template it declares is valid and present
+func validateTree(dir string) error {
+	var templateFile TemplateFile
+	if _, err := toml.DecodeFile(filepath.Join(dir, "templates.toml"), &templateFile); err != nil {
+		return errors.NewError("decode templates.toml", err)
+	}
+
+	var errs []error
+	for key, tpl := range templateFile.Templates {
+		if err := tpl.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("template '%s': %w", key, err))
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(dir, tpl.Path)); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("template '%s': %w: %s", key, errors.ErrTemplatePathMissing, tpl.Path))
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// swapLink points link at target by renaming a new symlink over it, which is atomic
+func swapLink(link, target string) error {
+	rel, err := filepath.Rel(filepath.Dir(link), target)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(filepath.Dir(link), "."+filepath.Base(link)+".swap")
+	os.Remove(tmp)
+	if err := os.Symlink(rel, tmp); err != nil {
+		return err
+	}
+
+	// Versions cached before the links were plain directories
+	if info, err := os.Lstat(link); err == nil && info.IsDir() {
+		if err := os.RemoveAll(link); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, link)
+}
+
+// materialize writes the files of commit into dest, keeping executable bits and symlinks,
+// and records the commit in dest/.jrx-revision
+func materialize(repo *git.Repository, commit plumbing.Hash, dest string) error {
+	c, err := repo.CommitObject(commit)
+	if err != nil {
+		return fmt.Errorf("read commit %s: %w", commit, err)
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return fmt.Errorf("read tree of %s: %w", commit, err)
+	}
+
+	err = tree.Files().ForEach(func(f *object.File) error {
+		path := filepath.Join(dest, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		reader, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		if f.Mode == filemode.Symlink {
+			target, err := io.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(string(target), path)
+		}
+
+		mode, err := f.Mode.ToOSFileMode()
+		if err != nil {
+			return err
+		}
+		out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, reader); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+	if err != nil {
+		return fmt.Errorf("write templates at %s: %w", commit, err)
+	}
+
+	return os.WriteFile(filepath.Join(dest, RevisionFile), []byte(commit.String()+"\n"), 0644)
+}
+
+// readRevision returns the commit recorded in a version directory, or "" if there is none
+func readRevision(versionDir string) string {
+	content, err := os.ReadFile(filepath.Join(versionDir, RevisionFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// pruneVersions removes the versions that are no longer cached, and the trees and revisions
+// checked out for upgrades that no version points at anymore. Versions such as feature/a live
+// in nested directories.
+func (tm *TemplateManager) pruneVersions(versions []string) error {
+	keep := make(map[string]bool, len(versions))
+	parents := make(map[string]bool)
+	trees := make(map[string]bool, len(versions))
+	for _, version := range versions {
+		version = filepath.ToSlash(version)
+		keep[version] = true
+		for dir := path.Dir(version); dir != "."; dir = path.Dir(dir) {
+			parents[dir] = true
+		}
+		trees[readRevision(filepath.Join(tm.config.TemplatesCacheDir, version))] = true
+	}
+
+	errs := tm.pruneLinks("", keep, parents)
+
+	entries, _ := os.ReadDir(filepath.Join(tm.config.TemplatesCacheDir, versionsDir))
+	for _, entry := range entries {
+		if !trees[entry.Name()] {
+			if err := os.RemoveAll(filepath.Join(tm.config.TemplatesCacheDir, versionsDir, entry.Name())); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	// Revisions are checked out again by the next upgrade that needs them
+	revisions, _ := os.ReadDir(filepath.Join(tm.config.TemplatesCacheDir, revisionsDir))
+	for _, entry := range revisions {
+		if trees[entry.Name()] {
+			continue
+		}
+		revision := filepath.Join(revisionsDir, entry.Name())
+		if err := os.RemoveAll(filepath.Join(tm.config.TemplatesCacheDir, revision)); err != nil {
+			errs = append(errs, err)
+		}
+		delete(tm.cache, revision)
+	}
+	return stderrors.Join(errs...)
+}
+
+// pruneLinks removes the version links below the cache directory rel that are not kept, and
+// the directories without any kept version. parents holds the directories of nested versions.
+func (tm *TemplateManager) pruneLinks(rel string, keep, parents map[string]bool) []error {
+	dir := filepath.Join(tm.config.TemplatesCacheDir, filepath.FromSlash(rel))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		version := path.Join(rel, entry.Name())
+		if keep[version] || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if !entry.IsDir() && entry.Type()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		// A directory holding kept versions, such as feature/ for feature/a
+		if entry.IsDir() && parents[version] {
+			errs = append(errs, tm.pruneLinks(version, keep, parents)...)
+			continue
+		}
+
+		log.Printf("Removing templates '%s', no longer a cached version\n", version)
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// mirrorTags returns the tags of the mirror, or nil if it was never fetched
+func (tm *TemplateManager) mirrorTags() ([]string, bool) {
+	repo, err := git.PlainOpen(filepath.Join(tm.config.TemplatesCacheDir, mirrorDir))
+	if err != nil {
+		return nil, false
+	}
+	iter, err := repo.Tags()
+	if err != nil {
+		return nil, false
+	}
+	var tags []string
+	iter.ForEach(func(ref *plumbing.Reference) error {
+		tags = append(tags, ref.Name().Short())
+		return nil
+	})
+	return tags, true
+}
+
+// checkoutRevision materializes a commit of the templates repository into dest,
+// fetching the mirror first if the commit is not known yet
+func (tm *TemplateManager) checkoutRevision(commit, dest string) error {
+	log.Printf("Checking out templates revision %s...\n", commit)
+
+	repo, err := tm.openMirror()
+	if err != nil {
+		return errors.NewError("open templates mirror", err)
+	}
+	hash := plumbing.NewHash(commit)
+	if _, err := repo.CommitObject(hash); err != nil {
+		auth, err := tm.auth()
+		if err != nil {
+			return errors.NewError("create SSH keys", err)
+		}
+		if err := fetchMirror(repo, auth); err != nil {
+			return errors.NewError("fetch template repository", err)
+		}
+	}
+
+	if err := materialize(repo, hash, dest); err != nil {
+		os.RemoveAll(dest)
+		return errors.NewError("checkout template revision", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/navigator-systems/jrx/internal/config"
+)
+
+func TestPruneVersions(t *testing.T) {
+	cache := t.TempDir()
+	tree := func(dir, commit string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(cache, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(cache, dir, RevisionFile), []byte(commit+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(version, commit string) {
+		t.Helper()
+		if err := swapLink(filepath.Join(cache, version), filepath.Join(cache, versionsDir, commit)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, commit := range []string{"aaa", "bbb", "ccc", "ddd"} {
+		tree(filepath.Join(versionsDir, commit), commit)
+	}
+	link("main", "aaa")
+	link("feature/a", "bbb")
+	link("feature/b", "ccc")
+	link("v1.0.0", "ddd")
+	tree(filepath.Join(revisionsDir, "aaa"), "aaa")
+	tree(filepath.Join(revisionsDir, "eee"), "eee")
+	if err := os.MkdirAll(filepath.Join(cache, ".sources", "team"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tm := NewTemplateManager(config.JRXConfig{TemplatesCacheDir: cache})
+	tm.cache[filepath.Join(revisionsDir, "eee")] = TemplatesSnapshot{}
+	if err := tm.pruneVersions([]string{"main", "feature/a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	kept := []string{"main", "feature/a", ".versions/aaa", ".versions/bbb", ".revisions/aaa", ".sources/team"}
+	removed := []string{"feature/b", "v1.0.0", ".versions/ccc", ".versions/ddd", ".revisions/eee"}
+	for _, path := range kept {
+		if _, err := os.Stat(filepath.Join(cache, path)); err != nil {
+			t.Errorf("%s was removed: %v", path, err)
+		}
+	}
+	for _, path := range removed {
+		if _, err := os.Lstat(filepath.Join(cache, path)); !os.IsNotExist(err) {
+			t.Errorf("%s was kept", path)
+		}
+	}
+	if _, ok := tm.cache[filepath.Join(revisionsDir, "eee")]; ok {
+		t.Errorf("the pruned revision is still in the templates cache")
+	}
+}
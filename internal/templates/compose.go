@@ -0,0 +1,145 @@
+package templates
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// composeTemplates resolves the extends and mixins of every template.
+// Templates that can't be composed keep their own definition and fail when used.
+func composeTemplates(raw map[string]RootTemplate) map[string]RootTemplate {
+	composed := make(map[string]RootTemplate, len(raw))
+	for key := range raw {
+		tpl, err := composeTemplate(raw, key, nil)
+		if err != nil {
+			log.Printf("Warning: could not compose template %s: %v", key, err)
+			tpl = raw[key]
+			tpl.composeErr = err
+		}
+		composed[key] = tpl
+	}
+	return composed
+}
+
+// composeTemplate overlays the layers of a template: the template it extends, then its mixins,
+// then the template itself. chain holds the templates being composed, to detect cycles.
+func composeTemplate(raw map[string]RootTemplate, key string, chain []string) (RootTemplate, error) {
+	if slices.Contains(chain, key) {
+		return RootTemplate{}, fmt.Errorf("cycle %s", strings.Join(append(chain, key), " -> "))
+	}
+	tpl, ok := raw[key]
+	if !ok {
+		return RootTemplate{}, fmt.Errorf("unknown template '%s' in %s", key, strings.Join(chain, " -> "))
+	}
+	// A layer missing part of its definition would silently drop it from the composed template
+	if tpl.loadErr != nil && len(chain) > 0 {
+		return RootTemplate{}, fmt.Errorf("template '%s': %w", key, tpl.loadErr)
+	}
+	chain = append(chain, key)
+
+	own := tpl
+	own.Layers = []TemplateLayer{{Name: key, Path: tpl.Path, Ignore: tpl.Ignore}}
+	if tpl.Extends == "" && len(tpl.Mixins) == 0 {
+		return own, nil
+	}
+
+	var layers []RootTemplate
+	if tpl.Extends != "" {
+		base, err := composeTemplate(raw, tpl.Extends, chain)
+		if err != nil {
+			return RootTemplate{}, err
+		}
+		layers = append(layers, base)
+	}
+	for _, mixin := range tpl.Mixins {
+		layer, err := composeTemplate(raw, mixin, chain)
+		if err != nil {
+			return RootTemplate{}, err
+		}
+		layers = append(layers, layer)
+	}
+	layers = append(layers, own)
+
+	composed := tpl
+	composed.Variables = nil
+	composed.ProjectInfo = ProjectTemplate{}
+	composed.Layers = nil
+	for _, layer := range layers {
+		for _, l := range layer.Layers {
+			// A layer reached twice (two mixins sharing a base) is rendered once
+			if !slices.ContainsFunc(composed.Layers, func(c TemplateLayer) bool { return c.Name == l.Name }) {
+				composed.Layers = append(composed.Layers, l)
+			}
+		}
+		composed.Variables = mergeVariables(composed.Variables, layer.Variables)
+		composed.ProjectInfo = mergeProjectInfo(composed.ProjectInfo, layer.ProjectInfo)
+	}
+	return composed, nil
+}
+
+// mergeVariables overlays variable definitions: fields set by a later layer replace earlier ones
+func mergeVariables(variables, overlay []VariablesTemplate) []VariablesTemplate {
+	merged := slices.Clone(variables)
+	for _, v := range overlay {
+		i := slices.IndexFunc(merged, func(m VariablesTemplate) bool { return m.Key == v.Key })
+		if i < 0 {
+			merged = append(merged, v)
+			continue
+		}
+		m := &merged[i]
+		if v.Description != "" {
+			m.Description = v.Description
+		}
+		// A static default and a computed one replace each other
+		if v.Default != "" || v.Expr != "" {
+			m.Default, m.Expr = v.Default, v.Expr
+		}
+		if v.Type != "" {
+			m.Type = v.Type
+		}
+		if v.requiredSet {
+			m.Required, m.requiredSet = v.Required, true
+		}
+		if len(v.Choices) > 0 {
+			m.Choices = v.Choices
+		}
+		if v.Pattern != "" {
+			m.Pattern = v.Pattern
+		}
+		if v.Min != nil {
+			m.Min = v.Min
+		}
+		if v.Max != nil {
+			m.Max = v.Max
+		}
+		if v.Help != "" {
+			m.Help = v.Help
+		}
+	}
+	return merged
+}
+
+// mergeProjectInfo overlays project.toml settings: values set by a later layer win,
+// rules, copy_verbatim patterns and hooks of all layers add up
+func mergeProjectInfo(info, overlay ProjectTemplate) ProjectTemplate {
+	if overlay.Language != "" {
+		info.Language = overlay.Language
+	}
+	if overlay.LanguageVersion != "" {
+		info.LanguageVersion = overlay.LanguageVersion
+	}
+	if overlay.Entry != "" {
+		info.Entry = overlay.Entry
+	}
+	if overlay.AppVersion != "" {
+		info.AppVersion = overlay.AppVersion
+	}
+	info.Rules = append(slices.Clone(info.Rules), overlay.Rules...)
+	info.CopyVerbatim = append(slices.Clone(info.CopyVerbatim), overlay.CopyVerbatim...)
+	info.Hooks.Pre = append(slices.Clone(info.Hooks.Pre), overlay.Hooks.Pre...)
+	info.Hooks.Post = append(slices.Clone(info.Hooks.Post), overlay.Hooks.Post...)
+	info.Strict = info.Strict || overlay.Strict
+	return info
+}
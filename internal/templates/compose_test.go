@@ -0,0 +1,91 @@
+package templates
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComposeKeepsLayerIgnoresApart(t *testing.T) {
+	tm := loadTestTemplates(t, map[string]string{
+		"templates.toml": `
+[templates.base]
+name = "base"
+path = "base"
+
+[templates.docker]
+name = "docker"
+path = "docker"
+
+[templates.svc]
+name = "svc"
+path = "svc"
+extends = "base"
+mixins = ["docker"]
+`,
+		"base/README.md":    "base",
+		"docker/.jrxignore": "README.md\n",
+		"docker/Dockerfile": "FROM scratch",
+		"svc/.jrxignore":    "*.tmp\n",
+	})
+
+	tpl, err := tm.GetTemplate("svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ignored := map[string][]string{}
+	for _, layer := range tpl.GetLayers() {
+		for _, path := range []string{"README.md", "notes.tmp", "Dockerfile"} {
+			if layer.IsIgnored(path, false) {
+				ignored[layer.Name] = append(ignored[layer.Name], path)
+			}
+		}
+	}
+
+	want := map[string][]string{"docker": {"README.md"}, "svc": {"notes.tmp"}}
+	if !reflect.DeepEqual(ignored, want) {
+		t.Errorf("ignored paths by layer = %v, want %v", ignored, want)
+	}
+}
+
+func TestComposeOverridesRequired(t *testing.T) {
+	tm := loadTestTemplates(t, map[string]string{
+		"templates.toml": `
+[templates.base]
+name = "base"
+path = "base"
+
+[templates.svc]
+name = "svc"
+path = "svc"
+extends = "base"
+`,
+		"base/vars.toml": `
+[variable.owner]
+required = true
+
+[variable.team]
+required = true
+`,
+		"svc/vars.toml": `
+[variable.owner]
+required = false
+
+[variable.team]
+description = "Owning team"
+`,
+	})
+
+	tpl, err := tm.GetTemplate("svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"owner": false, "team": true}
+	for _, variable := range tpl.Variables {
+		if variable.Required != want[variable.Key] {
+			t.Errorf("%s required = %v, want %v", variable.Key, variable.Required, want[variable.Key])
+		}
+	}
+	if len(tpl.Variables) != len(want) {
+		t.Errorf("got %d variables, want %d", len(tpl.Variables), len(want))
+	}
+}
@@ -0,0 +1,243 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/navigator-systems/jrx/internal/errors"
+)
+
+// isComputed reports whether a vars.toml default is a template expression
+func isComputed(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// ResolveComputed sets the variables left without a value to their computed default,
+// evaluating the expressions in dependency order. Variables in known were given explicitly,
+// even as an empty string, and are never computed.
+func (rt *RootTemplate) ResolveComputed(known map[string]string) error {
+	values, err := rt.ComputeValues(known)
+	if err != nil {
+		return err
+	}
+	rt.SetVariables(values)
+	return nil
+}
+
+// ComputeValues returns the value of every variable, given the values known so far.
+// Variables missing from known take their current value, and computed variables missing
+// from known and without a value are evaluated. Expressions see .ProjectName, every variable
+// as .<key>, .Vars and .Values, and getVariable.
+func (rt *RootTemplate) ComputeValues(known map[string]string) (map[string]string, error) {
+	values := make(map[string]string, len(rt.Variables))
+	pending := make(map[string]VariablesTemplate)
+	for _, variable := range rt.Variables {
+		value, ok := known[variable.Key]
+		if !ok {
+			value = variable.Default
+		}
+		values[variable.Key] = value
+		if !ok && value == "" && variable.Expr != "" {
+			pending[variable.Key] = variable
+		}
+	}
+
+	// Order the computed variables so that dependencies are evaluated first
+	var order []string
+	state := make(map[string]int) // 1 visiting, 2 done
+	var visit func(key string, chain []string) error
+	visit = func(key string, chain []string) error {
+		switch state[key] {
+		case 1:
+			return fmt.Errorf("%w: computed variables form a cycle: %s", errors.ErrInvalidVariable, strings.Join(append(chain, key), " -> "))
+		case 2:
+			return nil
+		}
+		state[key] = 1
+		deps, err := rt.expressionDeps(pending[key].Expr)
+		if err != nil {
+			return fmt.Errorf("%w '%s': %v", errors.ErrInvalidVariable, key, err)
+		}
+		for _, dep := range deps {
+			if _, isPending := pending[dep]; isPending {
+				if err := visit(dep, append(chain, key)); err != nil {
+					return err
+				}
+			}
+		}
+		state[key] = 2
+		order = append(order, key)
+		return nil
+	}
+	for _, variable := range rt.Variables {
+		if _, isPending := pending[variable.Key]; isPending {
+			if err := visit(variable.Key, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, key := range order {
+		value, err := rt.evalExpression(pending[key].Expr, values)
+		if err != nil {
+			return nil, fmt.Errorf("%w '%s': %v", errors.ErrInvalidVariable, key, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// expressionData is the data computed defaults are evaluated with, getVariable reads its .Vars
+type expressionData map[string]any
+
+// evalExpression executes a computed default with the current values
+func (rt *RootTemplate) evalExpression(expr string, values map[string]string) (string, error) {
+	tmpl, err := template.New("default").Funcs(rt.funcMap).Option("missingkey=error").Parse(expr)
+	if err != nil {
+		return "", err
+	}
+
+	typed := make(map[string]any, len(rt.Variables))
+	for _, variable := range rt.Variables {
+		variable.Default = values[variable.Key]
+		typed[variable.Key] = variable.TypedValue()
+	}
+	data := expressionData{"ProjectName": rt.ProjectName, "Values": typed, "Vars": typed}
+	for key, value := range typed {
+		data[key] = value
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// expressionDeps returns the variables a computed default refers to: .key, .Vars.key,
+// .Values.key, the same through $, index .Vars "key" and getVariable "key". Inside range and
+// with, . is no longer the expression data and only $ lookups count.
+func (rt *RootTemplate) expressionDeps(expr string) ([]string, error) {
+	tmpl, err := template.New("default").Funcs(rt.funcMap).Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var deps []string
+	var walkErr error
+	var walk func(node parse.Node, rebound bool)
+	walk = func(node parse.Node, rebound bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n != nil {
+				for _, child := range n.Nodes {
+					walk(child, rebound)
+				}
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, rebound)
+		case *parse.PipeNode:
+			if n != nil {
+				for _, cmd := range n.Cmds {
+					walk(cmd, rebound)
+				}
+			}
+		case *parse.CommandNode:
+			key, ok, err := indexedVariable(n, rebound)
+			if err != nil && walkErr == nil {
+				walkErr = err
+			}
+			if ok {
+				deps = append(deps, key)
+			}
+			for _, arg := range n.Args {
+				walk(arg, rebound)
+			}
+		case *parse.IfNode:
+			walk(n.Pipe, rebound)
+			walk(n.List, rebound)
+			walk(n.ElseList, rebound)
+		case *parse.RangeNode:
+			walk(n.Pipe, rebound)
+			walk(n.List, true)
+			walk(n.ElseList, rebound)
+		case *parse.WithNode:
+			walk(n.Pipe, rebound)
+			walk(n.List, true)
+			walk(n.ElseList, rebound)
+		case *parse.TemplateNode:
+			walk(n.Pipe, rebound)
+		case *parse.ChainNode:
+			walk(n.Node, rebound)
+		case *parse.FieldNode:
+			if key, ok := fieldVariable(n.Ident); ok && !rebound {
+				deps = append(deps, key)
+			}
+		case *parse.VariableNode:
+			if key, ok := fieldVariable(n.Ident[1:]); ok && n.Ident[0] == "$" {
+				deps = append(deps, key)
+			}
+		}
+	}
+	walk(tmpl.Tree.Root, false)
+	return deps, walkErr
+}
+
+// fieldVariable returns the variable a field chain of the expression data refers to,
+// .Vars and .Values alone don't name one
+func fieldVariable(ident []string) (string, bool) {
+	if len(ident) == 0 {
+		return "", false
+	}
+	if ident[0] == "Values" || ident[0] == "Vars" {
+		if len(ident) == 1 {
+			return "", false
+		}
+		return ident[1], true
+	}
+	return ident[0], true
+}
+
+// indexedVariable returns the variable read by getVariable, or by an index command on the
+// expression data or on .Vars and .Values. The key must be a quoted string, the order of
+// computed defaults can't be known from a key computed at run time.
+func indexedVariable(cmd *parse.CommandNode, rebound bool) (string, bool, error) {
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok {
+		return "", false, nil
+	}
+	if ident.Ident == "getVariable" && len(cmd.Args) > 1 {
+		key, ok := cmd.Args[1].(*parse.StringNode)
+		if !ok {
+			return "", false, fmt.Errorf("dynamic lookup '%s' is not supported, use a quoted key", cmd)
+		}
+		return key.Text, true, nil
+	}
+	if ident.Ident != "index" || len(cmd.Args) < 3 {
+		return "", false, nil
+	}
+
+	var data bool
+	switch container := cmd.Args[1].(type) {
+	case *parse.DotNode:
+		data = !rebound
+	case *parse.FieldNode:
+		data = !rebound && len(container.Ident) == 1 && (container.Ident[0] == "Vars" || container.Ident[0] == "Values")
+	case *parse.VariableNode:
+		data = slices.Equal(container.Ident, []string{"$"}) ||
+			slices.Equal(container.Ident, []string{"$", "Vars"}) || slices.Equal(container.Ident, []string{"$", "Values"})
+	}
+	if !data {
+		return "", false, nil
+	}
+
+	key, ok := cmd.Args[2].(*parse.StringNode)
+	if !ok {
+		return "", false, fmt.Errorf("dynamic lookup '%s' is not supported, use a quoted key or .Vars.<key>", cmd)
+	}
+	return key.Text, true, nil
+}
@@ -0,0 +1,181 @@
+package templates
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	jrxerrors "github.com/navigator-systems/jrx/internal/errors"
+)
+
+func TestExpressionDeps(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "field", expr: "{{ .org }}/{{ .ProjectName }}", want: []string{"org", "ProjectName"}},
+		{name: "vars and values", expr: "{{ .Vars.org }}-{{ .Values.port }}", want: []string{"org", "port"}},
+		{name: "dollar", expr: "{{ $.org }}{{ $.Vars.team }}", want: []string{"org", "team"}},
+		{name: "index", expr: `{{ index .Vars "my-org" }}{{ index $ "team" }}{{ index $.Values "port" }}`, want: []string{"my-org", "team", "port"}},
+		{name: "getVariable", expr: `{{ getVariable "org" . }}`, want: []string{"org"}},
+		{name: "function arguments", expr: `{{ default "acme" .org | kebabCase }}`, want: []string{"org"}},
+		{name: "if", expr: "{{ if .debug }}{{ .org }}{{ else }}{{ .team }}{{ end }}", want: []string{"debug", "org", "team"}},
+		{name: "range body", expr: `{{ range split "," .list }}{{ .Name }}{{ $.org }}{{ end }}`, want: []string{"list", "org"}},
+		{name: "with body", expr: "{{ with .org }}{{ .Inner }}{{ index . 0 }}{{ else }}{{ .team }}{{ end }}", want: []string{"org", "team"}},
+		{name: "index of a local value", expr: `{{ index (split "," .list) 0 }}`, want: []string{"list"}},
+		{name: "dynamic index", expr: "{{ index .Vars .key }}", wantErr: true},
+		{name: "dynamic getVariable", expr: "{{ getVariable .key . }}", wantErr: true},
+		{name: "syntax error", expr: "{{ .org ", wantErr: true},
+	}
+	rt := &RootTemplate{funcMap: buildFuncMap(nil)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rt.expressionDeps(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expressionDeps() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			slices.Sort(got)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !reflect.DeepEqual(slices.Compact(got), want) {
+				t.Errorf("expressionDeps() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestComputeValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables []VariablesTemplate
+		known     map[string]string
+		want      map[string]string
+		wantErr   string
+	}{
+		{
+			name: "dependency order",
+			variables: []VariablesTemplate{
+				{Key: "image", Expr: "{{ .registry }}/{{ .ProjectName }}"},
+				{Key: "registry", Expr: "ghcr.io/{{ .org }}"},
+				{Key: "org", Default: "acme"},
+			},
+			want: map[string]string{"image": "ghcr.io/acme/app", "registry": "ghcr.io/acme", "org": "acme"},
+		},
+		{
+			name: "known values win",
+			variables: []VariablesTemplate{
+				{Key: "org", Default: "acme"},
+				{Key: "registry", Expr: "ghcr.io/{{ .org }}"},
+				{Key: "image", Expr: "{{ .registry }}/{{ .ProjectName }}"},
+			},
+			known: map[string]string{"org": "corp", "registry": ""},
+			want:  map[string]string{"org": "corp", "registry": "", "image": "/app"},
+		},
+		{
+			name: "index and getVariable",
+			variables: []VariablesTemplate{
+				{Key: "full", Expr: `{{ getVariable "short-name" . }}.{{ index .Vars "domain" }}`},
+				{Key: "short-name", Expr: "{{ .ProjectName }}"},
+				{Key: "domain", Default: "example.com"},
+			},
+			want: map[string]string{"full": "app.example.com", "short-name": "app", "domain": "example.com"},
+		},
+		{
+			name: "typed values",
+			variables: []VariablesTemplate{
+				{Key: "port", Type: VarTypeInt, Default: "8080"},
+				{Key: "next", Expr: "{{ add1 .port }}"},
+			},
+			want: map[string]string{"port": "8080", "next": "8081"},
+		},
+		{
+			name: "cycle",
+			variables: []VariablesTemplate{
+				{Key: "a", Expr: "{{ .b }}"},
+				{Key: "b", Expr: "{{ .c }}"},
+				{Key: "c", Expr: "{{ $.a }}"},
+			},
+			wantErr: "a -> b -> c -> a",
+		},
+		{
+			name: "self reference",
+			variables: []VariablesTemplate{
+				{Key: "a", Expr: `{{ index .Vars "a" }}`},
+			},
+			wantErr: "a -> a",
+		},
+		{
+			name: "cycle broken by a known value",
+			variables: []VariablesTemplate{
+				{Key: "a", Expr: "{{ .b }}-a"},
+				{Key: "b", Expr: "{{ .a }}-b"},
+			},
+			known: map[string]string{"b": "given"},
+			want:  map[string]string{"a": "given-a", "b": "given"},
+		},
+		{
+			name: "unknown variable",
+			variables: []VariablesTemplate{
+				{Key: "a", Expr: "{{ .missing }}"},
+			},
+			wantErr: "missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			funcs := buildFuncMap(nil)
+			funcs["add1"] = func(n int) int { return n + 1 }
+			rt := &RootTemplate{ProjectName: "app", Variables: tt.variables, funcMap: funcs}
+
+			got, err := rt.ComputeValues(tt.known)
+			if tt.wantErr != "" {
+				if err == nil || !errors.Is(err, jrxerrors.ErrInvalidVariable) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ComputeValues() error = %v, want an invalid variable error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComputeValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyVariablesRecomputesDefaults(t *testing.T) {
+	rt := &RootTemplate{
+		ProjectName: "app",
+		funcMap:     buildFuncMap(nil),
+		Variables: []VariablesTemplate{
+			{Key: "org", Default: "acme"},
+			{Key: "module", Expr: "example.com/{{ .org }}"},
+			{Key: "pinned", Expr: "{{ .org }}"},
+		},
+	}
+	if err := rt.ApplyVariables(map[string]string{"pinned": "fixed"}); err != nil {
+		t.Fatal(err)
+	}
+	// Values set later, by pre hooks, are explicit too
+	if err := rt.ApplyVariables(map[string]string{"org": "corp"}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"org": "corp", "module": "example.com/corp", "pinned": "fixed"}
+	for key, value := range want {
+		if got := rt.GetVariable(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	if err := rt.ApplyVariables(map[string]string{"undeclared": "x"}); !errors.Is(err, jrxerrors.ErrUnknownVariable) {
+		t.Errorf("ApplyVariables() error = %v, want an unknown variable error", err)
+	}
+}
@@ -2,6 +2,7 @@ package templates
 
 import (
 	"path/filepath"
+	"text/template"
 
 	"github.com/navigator-systems/jrx/internal/errors"
 )
@@ -9,12 +10,29 @@ import (
 // Root: Template definition
 type RootTemplate struct {
 	ProjectName string
+	Key         string   `toml:"-"` // Key of the template in templates.toml
+	Source      string   `toml:"-"` // Template source the template comes from, "" for templates_repo
 	Name        string   `toml:"name"`
 	Description string   `toml:"description"`
 	Path        string   `toml:"path"`
 	Tags        []string `toml:"tags"`
 	ProjectInfo ProjectTemplate
 	Variables   []VariablesTemplate `toml:"variables"`
+	Extends     string              `toml:"extends,omitempty"` // Base template whose files are overlaid by this one
+	Mixins      []string            `toml:"mixins,omitempty"`  // Templates overlaid on the base, before this one
+	Ignore      []string            `toml:"-"`                 // Patterns from the template's .jrxignore
+	Layers      []TemplateLayer     `toml:"-"`                 // Resolved layers, in overlay order
+	loadErr     error               // Why project.toml or vars.toml could not be loaded
+	composeErr  error               // Why extends or mixins could not be resolved
+	funcMap     template.FuncMap    // Functions available to computed defaults
+	explicit    map[string]string   // Values given by ApplyVariables, never computed
+}
+
+// TemplateLayer is one of the template directories a composed template is made of
+type TemplateLayer struct {
+	Name   string   // Key of the template in templates.toml
+	Path   string   // Template directory, relative to the version directory
+	Ignore []string // Patterns from the layer's .jrxignore, matched against its own files only
 }
 
 // Validate checks if the template has all required fields
@@ -45,6 +63,19 @@ func (rt *RootTemplate) GetFullPath(baseDir, version string) string {
 	return fullPath
 }
 
+// QualifiedName returns the address of the template, <source>/<key> outside templates_repo
+func (rt *RootTemplate) QualifiedName() string {
+	return QualifiedName(rt.Source, rt.Key)
+}
+
+// GetLayers returns the template directories to render, in overlay order
+func (rt *RootTemplate) GetLayers() []TemplateLayer {
+	if len(rt.Layers) == 0 {
+		return []TemplateLayer{{Name: rt.Key, Path: rt.Path, Ignore: rt.Ignore}}
+	}
+	return rt.Layers
+}
+
 // GetVariableWithFallback returns the variable value or a fallback if not found
 func (rt *RootTemplate) GetVariableWithFallback(key, fallback string) string {
 	if val := rt.GetVariable(key); val != "" {
@@ -54,17 +85,30 @@ func (rt *RootTemplate) GetVariableWithFallback(key, fallback string) string {
 }
 
 type ProjectTemplate struct {
-	Language        string `toml:"language"`
-	LanguageVersion string `toml:"language_version,omitempty"`
-	Entry           string `toml:"entry"`
-	AppVersion      string `toml:"appversion,omitempty"`
+	Language        string     `toml:"language"`
+	LanguageVersion string     `toml:"language_version,omitempty"`
+	Entry           string     `toml:"entry"`
+	AppVersion      string     `toml:"appversion,omitempty"`
+	Rules           []FileRule `toml:"rules,omitempty"`         // Conditional inclusion of template paths
+	CopyVerbatim    []string   `toml:"copy_verbatim,omitempty"` // Patterns of files copied without rendering
+	Hooks           Hooks      `toml:"hooks,omitempty"`         // Commands and actions run around generation
+	Strict          bool       `toml:"strict,omitempty"`        // Fail rendering on undeclared variables
 }
 
 // Metadata fields used to substitute inside template files.
 type VariablesTemplate struct {
-	Key         string `toml:"key"`
-	Description string `toml:"description"`
-	Default     string `toml:"default,omitempty"`
+	Key         string   `toml:"key"`
+	Description string   `toml:"description"`
+	Default     string   `toml:"default,omitempty"`
+	Type        string   `toml:"type,omitempty"`     // string (default), int, bool or enum
+	Required    bool     `toml:"required,omitempty"` // The value can't be empty
+	Choices     []string `toml:"choices,omitempty"`  // Allowed values, required for enum
+	Pattern     string   `toml:"pattern,omitempty"`  // Regular expression the whole value must match
+	Min         *int     `toml:"min,omitempty"`      // Minimum value for int, minimum length for string
+	Max         *int     `toml:"max,omitempty"`      // Maximum value for int, maximum length for string
+	Help        string   `toml:"help,omitempty"`     // Longer explanation shown when asking for the value
+	Expr        string   `toml:"-"`                  // Template expression computing the default from other values
+	requiredSet bool     // required is set in vars.toml, even to false, and overrides earlier layers
 }
 
 type TemplateFile struct {
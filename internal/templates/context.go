@@ -0,0 +1,85 @@
+package templates
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/navigator-systems/jrx/internal/errors"
+)
+
+// RenderContext is the data template files, file names and hook arguments are rendered with.
+// It embeds the template so that .ProjectName, .Values and getVariable keep working.
+type RenderContext struct {
+	*RootTemplate
+	Vars     map[string]any  // Typed values of the declared variables, by key
+	Project  ProjectContext  // Project being generated
+	Template TemplateContext // Template the project is generated from
+	Meta     MetaContext     // Information about the jrx run
+	values   map[string]string
+}
+
+// ProjectContext describes the project being generated
+type ProjectContext struct {
+	Name string
+}
+
+// TemplateContext describes the template the project is generated from
+type TemplateContext struct {
+	Name    string // Key of the template in templates.toml
+	Version string // Branch or tag of the templates repository
+	Commit  string // Commit SHA the version resolved to, if known
+}
+
+// MetaContext describes the jrx run generating the project
+type MetaContext struct {
+	JRXVersion  string
+	GeneratedAt time.Time
+}
+
+// NewRenderContext creates the render context of a template with its current variable values
+func NewRenderContext(rt *RootTemplate, tmpl TemplateContext, meta MetaContext) *RenderContext {
+	values := make(map[string]string, len(rt.Variables))
+	for _, variable := range rt.Variables {
+		values[variable.Key] = variable.Default
+	}
+	return &RenderContext{
+		RootTemplate: rt,
+		Vars:         rt.Values(),
+		Project:      ProjectContext{Name: rt.ProjectName},
+		Template:     tmpl,
+		Meta:         meta,
+		values:       values,
+	}
+}
+
+// Strict reports whether referencing an undeclared variable fails rendering
+func (rc *RenderContext) Strict() bool {
+	return rc.ProjectInfo.Strict
+}
+
+// Lookup returns the value of a variable as a string. Undeclared variables are empty,
+// or an error in strict mode.
+func (rc *RenderContext) Lookup(key string) (string, error) {
+	value, ok := rc.values[key]
+	if !ok && rc.Strict() {
+		return "", fmt.Errorf("%w '%s'", errors.ErrUnknownVariable, key)
+	}
+	return value, nil
+}
+
+// lookupVariable implements getVariable for render contexts, bare templates and computed defaults
+func lookupVariable(key string, data any) (string, error) {
+	switch d := data.(type) {
+	case *RenderContext:
+		return d.Lookup(key)
+	case *RootTemplate:
+		return d.GetVariable(key), nil
+	case expressionData:
+		vars, _ := d["Vars"].(map[string]any)
+		if value, ok := vars[key]; ok {
+			return fmt.Sprint(value), nil
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("getVariable: expected the template context, got %T", data)
+}
@@ -1,11 +1,77 @@
 package templates
 
-func Index(slice []string, item int) string {
-	if item >= 0 && item < len(slice) {
-		return slice[item]
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
 
+	"gopkg.in/yaml.v3"
+)
+
+// Index returns an element of a collection like the built-in index, {{ index .Vars "my-key" }}
+// reads a map value and several indexes walk nested collections. An index past the end of a
+// slice returns an empty string.
+func Index(item any, indexes ...any) (any, error) {
+	value := reflect.ValueOf(item)
+	for _, index := range indexes {
+		for value.Kind() == reflect.Interface || value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return nil, fmt.Errorf("index of nil %s", value.Type())
+			}
+			value = value.Elem()
+		}
+
+		switch value.Kind() {
+		case reflect.Slice, reflect.Array, reflect.String:
+			i, ok := intIndex(index)
+			if !ok {
+				return nil, fmt.Errorf("cannot index %s with %T", value.Type(), index)
+			}
+			if i < 0 || i >= value.Len() {
+				return "", nil
+			}
+			value = value.Index(i)
+		case reflect.Map:
+			key := reflect.ValueOf(index)
+			if !key.IsValid() || !key.Type().AssignableTo(value.Type().Key()) {
+				return nil, fmt.Errorf("cannot index %s with %T", value.Type(), index)
+			}
+			elem := value.MapIndex(key)
+			if !elem.IsValid() {
+				elem = reflect.Zero(value.Type().Elem())
+			}
+			value = elem
+		case reflect.Invalid:
+			return nil, fmt.Errorf("index of untyped nil")
+		default:
+			return nil, fmt.Errorf("cannot index %s", value.Type())
+		}
 	}
-	return ""
+
+	if !value.IsValid() {
+		return nil, nil
+	}
+	return value.Interface(), nil
+}
+
+// intIndex converts an index argument of any integer type
+func intIndex(index any) (int, bool) {
+	value := reflect.ValueOf(index)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int(value.Uint()), true
+	}
+	return 0, false
 }
 
 func (rt *RootTemplate) GetVariable(key string) string {
@@ -20,3 +86,186 @@ func (rt *RootTemplate) GetVariable(key string) string {
 	}
 	return ""
 }
+
+// SetVariables overrides the values of the template variables present in values
+func (rt *RootTemplate) SetVariables(values map[string]string) {
+	for i := range rt.Variables {
+		if value, exists := values[rt.Variables[i].Key]; exists {
+			rt.Variables[i].Default = value
+		}
+	}
+}
+
+// words splits s into words at separators and case changes ("myHTTPServer-v2" -> my HTTP Server v2)
+func words(s string) []string {
+	var result []string
+	var current []rune
+	runes := []rune(s)
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, string(current))
+			current = nil
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := current[len(current)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return result
+}
+
+// capitalize upper-cases the first letter of a word and lower-cases the rest
+func capitalize(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes)
+}
+
+// joinWords lower-cases the words of s and joins them with sep
+func joinWords(s, sep string) string {
+	parts := words(s)
+	for i := range parts {
+		parts[i] = strings.ToLower(parts[i])
+	}
+	return strings.Join(parts, sep)
+}
+
+// SnakeCase converts s to snake_case
+func SnakeCase(s string) string {
+	return joinWords(s, "_")
+}
+
+// KebabCase converts s to kebab-case
+func KebabCase(s string) string {
+	return joinWords(s, "-")
+}
+
+// PascalCase converts s to PascalCase
+func PascalCase(s string) string {
+	parts := words(s)
+	for i := range parts {
+		parts[i] = capitalize(parts[i])
+	}
+	return strings.Join(parts, "")
+}
+
+// CamelCase converts s to camelCase
+func CamelCase(s string) string {
+	parts := words(s)
+	for i := range parts {
+		if i == 0 {
+			parts[i] = strings.ToLower(parts[i])
+		} else {
+			parts[i] = capitalize(parts[i])
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// Title upper-cases the first letter of every space separated word
+func Title(s string) string {
+	fields := strings.Split(s, " ")
+	for i, field := range fields {
+		runes := []rune(field)
+		if len(runes) > 0 {
+			runes[0] = unicode.ToUpper(runes[0])
+			fields[i] = string(runes)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// Default returns value, or def when value is empty ("", 0, false, nil or an empty list)
+func Default(def, value any) any {
+	if isEmpty(value) {
+		return def
+	}
+	return value
+}
+
+// isEmpty reports whether a template value is its zero value or an empty collection
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// Ternary returns a when cond is true and b otherwise
+func Ternary(a, b any, cond bool) any {
+	if cond {
+		return a
+	}
+	return b
+}
+
+// Date formats a time with a Go layout, e.g. {{ now | date "2006-01-02" }}
+func Date(layout string, t time.Time) string {
+	return t.Format(layout)
+}
+
+// UUID returns a random version 4 UUID
+func UUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// Sha256 returns the hex encoded SHA-256 of s
+func Sha256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// Indent prefixes every line of s with n spaces
+func Indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// ToJSON encodes v as JSON
+func ToJSON(v any) (string, error) {
+	out, err := json.Marshal(v)
+	return string(out), err
+}
+
+// ToYAML encodes v as YAML, without the trailing newline
+func ToYAML(v any) (string, error) {
+	out, err := yaml.Marshal(v)
+	return strings.TrimSuffix(string(out), "\n"), err
+}
+
+// envFunc returns the env template function, limited to variables matching one of the
+// allowed patterns (e.g. "CI_*")
+func envFunc(allowed []string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		for _, pattern := range allowed {
+			if ok, _ := path.Match(pattern, name); ok {
+				return os.Getenv(name), nil
+			}
+		}
+		return "", fmt.Errorf("environment variable %s is not in template_env_allow", name)
+	}
+}
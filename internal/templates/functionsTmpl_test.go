@@ -0,0 +1,73 @@
+package templates
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+func TestIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    any
+		indexes []any
+		want    any
+		wantErr bool
+	}{
+		{name: "slice", item: []string{"a", "b"}, indexes: []any{1}, want: "b"},
+		{name: "slice out of range", item: []string{"a"}, indexes: []any{3}, want: ""},
+		{name: "negative index", item: []string{"a"}, indexes: []any{-1}, want: ""},
+		{name: "map", item: map[string]any{"my-key": 8080}, indexes: []any{"my-key"}, want: 8080},
+		{name: "missing map key", item: map[string]string{}, indexes: []any{"x"}, want: ""},
+		{name: "nested", item: map[string][]int{"ports": {80, 443}}, indexes: []any{"ports", 1}, want: 443},
+		{name: "no index", item: "value", want: "value"},
+		{name: "wrong key type", item: map[string]string{}, indexes: []any{1}, wantErr: true},
+		{name: "not indexable", item: 42, indexes: []any{0}, wantErr: true},
+		{name: "nil", item: nil, indexes: []any{0}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Index(tt.item, tt.indexes...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Index() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Index() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderIndexVars(t *testing.T) {
+	rt := &RootTemplate{
+		ProjectName: "app",
+		Variables: []VariablesTemplate{
+			{Key: "dashed-key", Default: "value"},
+			{Key: "port", Type: VarTypeInt, Default: "8080"},
+		},
+	}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: `{{ index .Vars "dashed-key" }}`, want: "value"},
+		{text: `{{ index .Vars "port" | printf "%d" }}`, want: "8080"},
+		{text: `{{ index (split "," "a,b") 1 }}`, want: "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			tmpl, err := template.New("file").Funcs(buildFuncMap(nil)).Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, NewRenderContext(rt, TemplateContext{}, MetaContext{})); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("rendered %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
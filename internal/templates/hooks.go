@@ -0,0 +1,99 @@
+package templates
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Built-in hook actions. They only touch files inside the generated project.
+const (
+	HookActionChmod  = "chmod"
+	HookActionRename = "rename"
+	HookActionDelete = "delete"
+)
+
+// Hooks are the steps a template runs around file generation
+type Hooks struct {
+	Pre  []Hook `toml:"pre"`  // Run before rendering, to validate or compute variables
+	Post []Hook `toml:"post"` // Run on the generated files, before the initial commit
+}
+
+// Hook is a command or a built-in action declared in a template's project.toml.
+// Arguments and paths may contain template expressions.
+type Hook struct {
+	Name   string         `toml:"name"`
+	Run    []string       `toml:"run"`    // Command and arguments
+	Action string         `toml:"action"` // chmod, rename or delete
+	Path   string         `toml:"path"`   // File the action applies to, relative to the project
+	To     string         `toml:"to"`     // New path for rename
+	Mode   string         `toml:"mode"`   // Octal mode for chmod, e.g. "0755"
+	When   map[string]any `toml:"when"`   // Run only if every condition holds, as in rules
+	Layer  string         `toml:"-"`      // Path of the template that declared the hook
+}
+
+// IsCommand reports whether the hook runs an external command
+func (h Hook) IsCommand() bool {
+	return len(h.Run) > 0
+}
+
+// Label returns the hook name, or a description of what it does
+func (h Hook) Label() string {
+	switch {
+	case h.Name != "":
+		return h.Name
+	case h.IsCommand():
+		return strings.Join(h.Run, " ")
+	case h.Action == HookActionRename:
+		return fmt.Sprintf("rename %s to %s", h.Path, h.To)
+	case h.Action == HookActionChmod:
+		return fmt.Sprintf("chmod %s %s", h.Mode, h.Path)
+	default:
+		return fmt.Sprintf("%s %s", h.Action, h.Path)
+	}
+}
+
+// Validate checks that the hook is either a command or a known action with its arguments
+func (h Hook) Validate() error {
+	if h.IsCommand() {
+		if h.Action != "" {
+			return fmt.Errorf("hook '%s': run and action are exclusive", h.Label())
+		}
+		return nil
+	}
+	switch h.Action {
+	case HookActionDelete:
+	case HookActionChmod:
+		if h.Mode == "" {
+			return fmt.Errorf("hook '%s': chmod without mode", h.Label())
+		}
+	case HookActionRename:
+		if h.To == "" {
+			return fmt.Errorf("hook '%s': rename without to", h.Label())
+		}
+	case "":
+		return fmt.Errorf("hook '%s': neither run nor action", h.Label())
+	default:
+		return fmt.Errorf("hook '%s': unknown action '%s'", h.Label(), h.Action)
+	}
+	if h.Path == "" {
+		return fmt.Errorf("hook '%s': %s without path", h.Label(), h.Action)
+	}
+	return nil
+}
+
+// HookEnabled reports whether the hook conditions hold for the current variable values
+func (rt *RootTemplate) HookEnabled(h Hook) bool {
+	return conditionsHold(h.When, rt.Values())
+}
+
+// CommandHooks returns the pre and post hooks that run external commands
+func (rt *RootTemplate) CommandHooks() []Hook {
+	var hooks []Hook
+	for _, h := range append(slices.Clone(rt.ProjectInfo.Hooks.Pre), rt.ProjectInfo.Hooks.Post...) {
+		if h.IsCommand() && rt.HookEnabled(h) {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
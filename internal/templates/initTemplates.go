@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 
 	"github.com/navigator-systems/jrx/internal/config"
 	"github.com/navigator-systems/jrx/internal/errors"
@@ -22,11 +21,14 @@ func InitTemplates() {
 
 	fmt.Println("JRX config loaded successfully")
 
-	// Create template manager
-	tm := NewTemplateManager(jrxConfig)
+	// Create the template managers of every source
+	sources, err := NewSources(jrxConfig)
+	if err != nil {
+		log.Printf("Warning: could not load all template sources: %v\n", err)
+	}
 
 	// Initialize (clone) templates
-	if err := tm.Initialize(); err != nil {
+	if err := sources.Initialize(); err != nil {
 		fmt.Printf("Error initializing templates: %v\n", err)
 		return
 	}
@@ -42,13 +44,3 @@ func CreateCacheDir(versionDir string) error {
 	}
 	return nil
 }
-
-func CreateDirsIfNotExist(parentDir string, dirs []string) error {
-	for _, dir := range dirs {
-		fullPath := filepath.Join(parentDir, dir)
-		if err := os.MkdirAll(fullPath, 0755); err != nil {
-			return errors.NewError(fmt.Sprintf("create directory %s", fullPath), errors.ErrCannotCreateDirectory)
-		}
-	}
-	return nil
-}
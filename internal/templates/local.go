@@ -0,0 +1,109 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+// LocalVersion is the version of templates read from a local directory instead of the cache
+const LocalVersion = "local"
+
+// VersionDir returns the directory holding the templates of a version
+func VersionDir(templatesDir, version string) string {
+	if version == LocalVersion {
+		return templatesDir
+	}
+	return filepath.Join(templatesDir, version)
+}
+
+// localDir returns the directory templates are read from directly, without downloading them:
+// templates_dir (or --templates-dir), or a templates_repo that is a file:// URL or a local
+// working copy with a templates.toml at its root
+func (tm *TemplateManager) localDir() (string, bool) {
+	if tm.config.TemplatesDir != "" {
+		return strings.TrimPrefix(tm.config.TemplatesDir, "file://"), true
+	}
+
+	repo := tm.config.TemplatesRepo
+	if path, ok := strings.CutPrefix(repo, "file://"); ok {
+		return path, true
+	}
+	if filepath.IsAbs(repo) || strings.HasPrefix(repo, ".") {
+		if _, err := os.Stat(filepath.Join(repo, "templates.toml")); err == nil {
+			return repo, true
+		}
+	}
+	return "", false
+}
+
+// IsLocal reports whether templates are read from a local directory
+func (tm *TemplateManager) IsLocal() bool {
+	_, ok := tm.localDir()
+	return ok
+}
+
+// hasLocalChanges reports whether the working copy of repo has uncommitted or untracked
+// files below dir
+func hasLocalChanges(repo *git.Repository, dir string) (bool, error) {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return false, err
+	}
+
+	root, err := filepath.EvalSymlinks(worktree.Filesystem.Root())
+	if err != nil {
+		return false, err
+	}
+	target, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return false, err
+	}
+	prefix := filepath.ToSlash(rel)
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return false, err
+	}
+
+	for file, fileStatus := range status {
+		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified {
+			continue
+		}
+		if prefix != "." && file != prefix && !strings.HasPrefix(file, prefix+"/") {
+			continue
+		}
+		// go-git reports links to absolute paths as modified, compare their target with the index
+		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Modified && unchangedLink(idx, root, file) {
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// unchangedLink reports whether file is a symbolic link whose target matches the index
+func unchangedLink(idx *index.Index, root, file string) bool {
+	entry, err := idx.Entry(file)
+	if err != nil || entry.Mode != filemode.Symlink {
+		return false
+	}
+	target, err := os.Readlink(filepath.Join(root, filepath.FromSlash(file)))
+	if err != nil {
+		return false
+	}
+	return plumbing.ComputeHash(plumbing.BlobObject, []byte(target)) == entry.Hash
+}
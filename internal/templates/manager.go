@@ -1,21 +1,34 @@
 package templates
 
 import (
+	"encoding/base64"
+	stderrors "errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/BurntSushi/toml"
-	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/navigator-systems/jrx/internal/config"
 	"github.com/navigator-systems/jrx/internal/errors"
 )
 
+// IgnoreFile lists, in gitignore syntax, the template files that are not emitted into projects
+const IgnoreFile = ".jrxignore"
+
+// PartialsDir holds {{define}} blocks shared by every file render, at the root of the
+// templates repository and inside each template
+const PartialsDir = "_partials"
+
+// revisionsDir holds the pinned revisions checked out inside the cache directory
+const revisionsDir = ".revisions"
+
 type TemplatesSnapshot struct {
 	Templates map[string]RootTemplate
 	Count     int
@@ -36,24 +49,54 @@ type TemplateManager struct {
 func NewTemplateManager(cfg config.JRXConfig) *TemplateManager {
 	return &TemplateManager{
 		config:  cfg,
-		funcMap: buildFuncMap(),
+		funcMap: buildFuncMap(cfg.TemplateEnvAllow),
 		loaded:  false,
 		cache:   make(map[string]TemplatesSnapshot),
 	}
 }
 
-// buildFuncMap creates the function map for template execution
-func buildFuncMap() template.FuncMap {
+// buildFuncMap creates the function map for template execution.
+// env only reads the environment variables allowed by template_env_allow.
+func buildFuncMap(envAllow []string) template.FuncMap {
 	return template.FuncMap{
-		"index": Index,
-		"getVariable": func(key string, rt *RootTemplate) string {
-			return rt.GetVariable(key)
-		},
-		"join":      strings.Join,
-		"toLower":   strings.ToLower,
-		"toUpper":   strings.ToUpper,
-		"hasPrefix": strings.HasPrefix,
-		"hasSuffix": strings.HasSuffix,
+		"index":       Index,
+		"getVariable": lookupVariable,
+		"join":        strings.Join,
+		"toLower":     strings.ToLower,
+		"toUpper":     strings.ToUpper,
+		"hasPrefix":   strings.HasPrefix,
+		"hasSuffix":   strings.HasSuffix,
+
+		// Casing
+		"snakeCase":  SnakeCase,
+		"camelCase":  CamelCase,
+		"kebabCase":  KebabCase,
+		"pascalCase": PascalCase,
+		"title":      Title,
+
+		// Strings, arguments ordered for pipelines: {{ .ProjectName | replace "-" "_" }}
+		"replace":  func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"trim":     strings.TrimSpace,
+		"split":    func(sep, s string) []string { return strings.Split(s, sep) },
+		"contains": func(substr, s string) bool { return strings.Contains(s, substr) },
+		"quote":    func(s string) string { return fmt.Sprintf("%q", s) },
+		"indent":   Indent,
+		"nindent":  func(n int, s string) string { return "\n" + Indent(n, s) },
+
+		// Values
+		"default": Default,
+		"ternary": Ternary,
+
+		// Dates, ids and encodings
+		"now":    time.Now,
+		"date":   Date,
+		"uuid":   UUID,
+		"sha256": Sha256,
+		"b64enc": func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"toJson": ToJSON,
+		"toYaml": ToYAML,
+
+		"env": envFunc(envAllow),
 	}
 }
 
@@ -62,81 +105,88 @@ func (tm *TemplateManager) GetFuncMap() template.FuncMap {
 	return tm.funcMap
 }
 
-// Initialize clones the template repository
+// Initialize updates the template cache from the templates repository.
+// A bare mirror in <cache>/.mirror fetches only new objects, then each configured branch and
+// tag whose ref moved is materialized, validated and swapped in as <cache>/<version>.
+// Versions that fail keep their previous templates.
 func (tm *TemplateManager) Initialize() error {
-	log.Println("Downloading templates...")
-
-	// Remove existing templates directory if it exists
-	if _, err := os.Stat(tm.config.TemplatesCacheDir); err == nil {
-		if err := os.RemoveAll(tm.config.TemplatesCacheDir); err != nil {
-			return errors.NewError("remove templates directory", err)
-		}
+	if dir, ok := tm.localDir(); ok {
+		log.Printf("Templates are read from %s, nothing to download\n", dir)
+		return nil
 	}
+	log.Println("Downloading templates...")
 
 	//Create cache directory if it doesn't exist
 	if err := CreateCacheDir(tm.config.TemplatesCacheDir); err != nil {
 		return errors.NewError("create cache directory", err)
 	}
 
-	// Create directories for branch versions
-	if err := CreateDirsIfNotExist(tm.config.TemplatesCacheDir, tm.config.TemplatesBranch); err != nil {
-		return errors.NewError("create version directories", err)
+	// Setup SSH authentication
+	publicKeys, err := tm.auth()
+	if err != nil {
+		return errors.NewError("create SSH keys", err)
 	}
 
-	// Get versions of tags to clone based on pattern and max versions
-	tagVersions, err := tm.GetVersionsTags()
+	repo, err := tm.openMirror()
 	if err != nil {
-		return errors.NewError("get tag versions", err)
+		return errors.NewError("open templates mirror", err)
 	}
-	//Create directories for tag versions
-	if err := CreateDirsIfNotExist(tm.config.TemplatesCacheDir, tagVersions); err != nil {
-		return errors.NewError("create version directories", err)
+	if err := fetchMirror(repo, publicKeys); err != nil {
+		// Keep serving the cache of the last successful download
+		return errors.NewError("fetch template repository", fmt.Errorf("%w, keeping the cached templates", err))
 	}
 
-	// Setup SSH authentication
-	publicKeys, err := ssh.NewPublicKeysFromFile("git", tm.config.SshKeyPath, tm.config.SshKeyPassphrase)
+	// Get versions of tags to cache based on pattern and max versions
+	tagVersions, err := tm.GetVersionsTags()
 	if err != nil {
-		return errors.NewError("create SSH keys", err)
+		return errors.NewError("get tag versions", err)
 	}
 
-	// Clone the repository for each branch
+	refs := make(map[string]plumbing.ReferenceName)
 	for _, branch := range tm.config.TemplatesBranch {
-		repoDest := filepath.Join(tm.config.TemplatesCacheDir, branch)
-		_, err = git.PlainClone(repoDest, false, &git.CloneOptions{
-			URL:           tm.config.TemplatesRepo,
-			ReferenceName: plumbing.NewBranchReferenceName(branch),
-			SingleBranch:  true,
-			Auth:          publicKeys,
-			Depth:         1,
-		})
+		refs[branch] = plumbing.NewBranchReferenceName(branch)
 	}
-
-	// Clone the repository for each tag
 	for _, tag := range tagVersions {
-		repoDest := filepath.Join(tm.config.TemplatesCacheDir, tag)
-		_, err = git.PlainClone(repoDest, false, &git.CloneOptions{
-			URL:           tm.config.TemplatesRepo,
-			ReferenceName: plumbing.NewTagReferenceName(tag),
-			SingleBranch:  true,
-			Auth:          publicKeys,
-			Depth:         1,
-		})
+		refs[tag] = plumbing.NewTagReferenceName(tag)
 	}
 
-	if err != nil {
-		return errors.NewError("clone template repository", err)
+	// Materialize every version, reporting each one that fails
+	var errs []error
+	versions := append(slices.Clone(tm.config.TemplatesBranch), tagVersions...)
+	for _, version := range versions {
+		commit, err := resolveVersion(repo, refs[version])
+		if err == nil {
+			err = tm.syncVersion(repo, version, commit)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", version, err))
+		}
 	}
+	if err := tm.pruneVersions(versions); err != nil {
+		errs = append(errs, err)
+	}
+	os.RemoveAll(filepath.Join(tm.config.TemplatesCacheDir, stagingDir))
 
-	log.Printf("Successfully cloned templates from '%s'\n", tm.config.TemplatesRepo)
+	if len(errs) > 0 {
+		return errors.NewError("update template cache", stderrors.Join(errs...))
+	}
+
+	log.Printf("Successfully updated templates from '%s'\n", tm.config.TemplatesRepo)
 	return nil
 }
 
+// auth returns the SSH credentials for the templates repository
+func (tm *TemplateManager) auth() (*ssh.PublicKeys, error) {
+	return ssh.NewPublicKeysFromFile("git", tm.config.SshKeyPath, tm.config.SshKeyPassphrase)
+}
+
 // LoadTemplates loads all templates from the templates directory
 func (tm *TemplateManager) LoadTemplates(templatesVersion string) error {
 	log.Println("Loading templates...")
 
-	if templatesVersion == "" {
-		templatesVersion = tm.config.TemplatesDefault
+	templatesVersion, err := tm.ResolveVersion(templatesVersion)
+	if err != nil {
+		return err
 	}
 	log.Println("Template version is:", templatesVersion)
 
@@ -144,7 +194,13 @@ func (tm *TemplateManager) LoadTemplates(templatesVersion string) error {
 		return errors.NewError("Load templates", fmt.Errorf("Version %s is not available", templatesVersion))
 	}
 
-	if snapshot, ok := tm.cache[templatesVersion]; ok {
+	return tm.loadVersion(templatesVersion)
+}
+
+// loadVersion loads the templates of a version directory inside the cache
+func (tm *TemplateManager) loadVersion(templatesVersion string) error {
+	// Local templates are being edited, they are read again on every load
+	if snapshot, ok := tm.cache[templatesVersion]; ok && templatesVersion != LocalVersion {
 		tm.templateFile.Templates = snapshot.Templates
 		tm.loaded = true
 		tm.currentVersion = templatesVersion
@@ -152,25 +208,30 @@ func (tm *TemplateManager) LoadTemplates(templatesVersion string) error {
 		return nil
 	}
 
-	templatePath := filepath.Join(tm.config.TemplatesCacheDir, templatesVersion, "templates.toml")
+	templatePath := filepath.Join(VersionDir(tm.GetTemplatesDir(), templatesVersion), "templates.toml")
 	if _, err := os.Stat(templatePath); err != nil {
 		return errors.NewError("find templates.toml", errors.ErrConfigNotFound)
 	}
 
 	// Decode the main template file
-	if _, err := toml.DecodeFile(templatePath, &tm.templateFile); err != nil {
+	var templateFile TemplateFile
+	if _, err := toml.DecodeFile(templatePath, &templateFile); err != nil {
 		return errors.NewError("decode templates.toml", err)
 	}
+	tm.templateFile = templateFile
 
 	// Process each template to load additional configuration files
 	for templateKey, tpl := range tm.templateFile.Templates {
-		baseDir := filepath.Join(tm.config.TemplatesCacheDir, templatesVersion, tpl.Path)
+		tpl.Key = templateKey
+		tpl.Source = tm.config.TemplatesSource
+		baseDir := filepath.Join(VersionDir(tm.GetTemplatesDir(), templatesVersion), tpl.Path)
 
 		// Load project.toml if it exists
 		projectPath := filepath.Join(baseDir, "project.toml")
 		if _, err := os.Stat(projectPath); err == nil {
 			if err := tm.loadProjectConfig(templateKey, projectPath, &tpl); err != nil {
 				log.Printf("Warning: could not load project.toml for %s: %v", templateKey, err)
+				tpl.loadErr = err
 			}
 		}
 
@@ -179,13 +240,22 @@ func (tm *TemplateManager) LoadTemplates(templatesVersion string) error {
 		if _, err := os.Stat(varsPath); err == nil {
 			if err := tm.loadVarsConfig(templateKey, varsPath, &tpl); err != nil {
 				log.Printf("Warning: could not load vars.toml for %s: %v", templateKey, err)
+				tpl.loadErr = err
 			}
 		}
 
+		// Load .jrxignore if it exists
+		if content, err := os.ReadFile(filepath.Join(baseDir, IgnoreFile)); err == nil {
+			tpl.Ignore = ParseIgnoreFile(string(content))
+		}
+
 		// Update the template in the map
 		tm.templateFile.Templates[templateKey] = tpl
 	}
 
+	// Resolve extends and mixins once every layer is loaded
+	tm.templateFile.Templates = composeTemplates(tm.templateFile.Templates)
+
 	tm.loaded = true
 	tm.currentVersion = templatesVersion
 	log.Printf("Successfully loaded %d templates\n", len(tm.templateFile.Templates))
@@ -198,6 +268,30 @@ func (tm *TemplateManager) LoadTemplates(templatesVersion string) error {
 	return nil
 }
 
+// LoadRevision loads the templates as they were at a commit of the templates repository.
+// The commit is materialized once into <cache>/.revisions/<sha> and reused afterwards.
+// It returns the version directory to hand to the project generator.
+func (tm *TemplateManager) LoadRevision(commit string) (string, error) {
+	if tm.IsLocal() {
+		log.Printf("Warning: templates are read from a local directory, using them instead of revision %s\n", commit)
+		return LocalVersion, tm.loadVersion(LocalVersion)
+	}
+
+	version := filepath.Join(revisionsDir, commit)
+	versionDir := filepath.Join(tm.config.TemplatesCacheDir, version)
+
+	if _, err := os.Stat(versionDir); err != nil {
+		if err := tm.checkoutRevision(commit, versionDir); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tm.loadVersion(version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
 // loadProjectConfig loads and decodes project.toml into the template's ProjectInfo
 func (tm *TemplateManager) loadProjectConfig(templateKey, filePath string, tpl *RootTemplate) error {
 	var projectInfo ProjectTemplate
@@ -205,32 +299,73 @@ func (tm *TemplateManager) loadProjectConfig(templateKey, filePath string, tpl *
 		return fmt.Errorf("error decoding project.toml: %w", err)
 	}
 
+	// Hook commands run relative to the template that declares them
+	for _, hooks := range [][]Hook{projectInfo.Hooks.Pre, projectInfo.Hooks.Post} {
+		for i := range hooks {
+			hooks[i].Layer = tpl.Path
+		}
+	}
+
 	tpl.ProjectInfo = projectInfo
 	return nil
 }
 
-// loadVarsConfig loads and decodes vars.toml into the template's Variables
+// loadVarsConfig loads and decodes vars.toml into the template's Variables, in declaration order.
+// Variables are only set when all of them are valid.
 func (tm *TemplateManager) loadVarsConfig(templateKey, filePath string, tpl *RootTemplate) error {
 	var varsConfig struct {
 		Variable map[string]struct {
-			Default     string `toml:"default"`
-			Description string `toml:"description"`
+			Default     any      `toml:"default"`
+			Description string   `toml:"description"`
+			Type        string   `toml:"type"`
+			Required    *bool    `toml:"required"`
+			Choices     []string `toml:"choices"`
+			Pattern     string   `toml:"pattern"`
+			Min         *int     `toml:"min"`
+			Max         *int     `toml:"max"`
+			Help        string   `toml:"help"`
 		} `toml:"variable"`
 	}
 
-	if _, err := toml.DecodeFile(filePath, &varsConfig); err != nil {
+	md, err := toml.DecodeFile(filePath, &varsConfig)
+	if err != nil {
 		return fmt.Errorf("error decoding vars.toml: %w", err)
 	}
 
-	tpl.Variables = make([]VariablesTemplate, 0, len(varsConfig.Variable))
-	for key, varInfo := range varsConfig.Variable {
-		tpl.Variables = append(tpl.Variables, VariablesTemplate{
+	variables := make([]VariablesTemplate, 0, len(varsConfig.Variable))
+	for _, tomlKey := range md.Keys() {
+		if len(tomlKey) != 2 || tomlKey[0] != "variable" {
+			continue
+		}
+		key := tomlKey[1]
+		varInfo := varsConfig.Variable[key]
+
+		variable := VariablesTemplate{
 			Key:         key,
 			Description: varInfo.Description,
-			Default:     varInfo.Default,
-		})
+			Type:        varInfo.Type,
+			Required:    varInfo.Required != nil && *varInfo.Required,
+			requiredSet: varInfo.Required != nil,
+			Choices:     varInfo.Choices,
+			Pattern:     varInfo.Pattern,
+			Min:         varInfo.Min,
+			Max:         varInfo.Max,
+			Help:        varInfo.Help,
+		}
+		if varInfo.Default != nil {
+			variable.Default = fmt.Sprint(varInfo.Default)
+		}
+		// Defaults with template expressions are computed once the other values are known
+		if isComputed(variable.Default) {
+			variable.Expr, variable.Default = variable.Default, ""
+		}
+		if err := variable.checkDefinition(); err != nil {
+			return err
+		}
+		variables = append(variables, variable)
 	}
 
+	tpl.Variables = variables
 	return nil
 }
 
@@ -248,6 +383,16 @@ func (tm *TemplateManager) GetTemplate(name string) (*RootTemplate, error) {
 	if err := tpl.Validate(); err != nil {
 		return nil, err
 	}
+	if tpl.loadErr != nil {
+		return nil, errors.NewError("load template", tpl.loadErr)
+	}
+	if tpl.composeErr != nil {
+		return nil, errors.NewError("compose template", tpl.composeErr)
+	}
+
+	// Copy the variables so user values don't leak into the cached snapshot
+	tpl.Variables = slices.Clone(tpl.Variables)
+	tpl.funcMap = tm.funcMap
 
 	return &tpl, nil
 }
@@ -265,8 +410,21 @@ func (tm *TemplateManager) GetTemplatesMap() map[string]RootTemplate {
 	return tm.templateFile.Templates
 }
 
-// GetTemplatesDir returns the templates directory path
+// Config returns the configuration of the template source the manager reads from
+func (tm *TemplateManager) Config() config.JRXConfig {
+	return tm.config
+}
+
+// Source returns the name of the template source, "" for the top-level templates_repo
+func (tm *TemplateManager) Source() string {
+	return tm.config.TemplatesSource
+}
+
+// GetTemplatesDir returns the templates directory path: the cache, or the local templates directory
 func (tm *TemplateManager) GetTemplatesDir() string {
+	if dir, ok := tm.localDir(); ok {
+		return dir
+	}
 	return tm.config.TemplatesCacheDir
 }
 
@@ -0,0 +1,65 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/navigator-systems/jrx/internal/config"
+)
+
+// loadTestTemplates writes files to a local templates directory and loads them
+func loadTestTemplates(t *testing.T, files map[string]string) *TemplateManager {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tm := NewTemplateManager(config.JRXConfig{TemplatesDir: dir})
+	if err := tm.LoadTemplates(""); err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+func TestGetTemplateReportsInvalidVariables(t *testing.T) {
+	tm := loadTestTemplates(t, map[string]string{
+		"templates.toml": `
+[templates.app]
+name = "app"
+path = "app"
+
+[templates.web]
+name = "web"
+path = "web"
+extends = "app"
+`,
+		"app/vars.toml": `
+[variable.first]
+default = "a"
+
+[variable.broken]
+type = "float"
+
+[variable.last]
+default = "z"
+`,
+	})
+
+	for _, name := range []string{"app", "web"} {
+		t.Run(name, func(t *testing.T) {
+			_, err := tm.GetTemplate(name)
+			if err == nil || !strings.Contains(err.Error(), "unknown type 'float'") {
+				t.Fatalf("GetTemplate(%q) error = %v, want the definition error", name, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package templates
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// FileRule includes template paths only when variables have the given values.
+// Conditions map a variable to a value, or to a list of accepted values.
+type FileRule struct {
+	Paths  []string       `toml:"paths"`  // gitignore style patterns, relative to the template root
+	When   map[string]any `toml:"when"`   // The paths are emitted only if every condition holds
+	Unless map[string]any `toml:"unless"` // The paths are left out if every condition holds
+}
+
+// ValidateRules checks that the rules only reference declared variables
+func (rt *RootTemplate) ValidateRules() error {
+	for i, rule := range rt.ProjectInfo.Rules {
+		if len(rule.Paths) == 0 {
+			return fmt.Errorf("rule %d: no paths", i+1)
+		}
+		for _, conditions := range []map[string]any{rule.When, rule.Unless} {
+			for key := range conditions {
+				if !slices.ContainsFunc(rt.Variables, func(v VariablesTemplate) bool { return v.Key == key }) {
+					return fmt.Errorf("rule %d: unknown variable '%s'", i+1, key)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ExcludedBy returns the first rule leaving the template path out of the project, if any
+func (rt *RootTemplate) ExcludedBy(relPath string, isDir bool) (FileRule, bool) {
+	segments := strings.Split(filepath.ToSlash(relPath), "/")
+	values := rt.Values()
+
+	for _, rule := range rt.ProjectInfo.Rules {
+		if !rule.matchesPath(segments, isDir) {
+			continue
+		}
+		if !rule.applies(values) {
+			return rule, true
+		}
+	}
+	return FileRule{}, false
+}
+
+// matchesPath reports whether one of the rule patterns matches the path
+func (r FileRule) matchesPath(segments []string, isDir bool) bool {
+	return matchPatterns(r.Paths, segments, isDir)
+}
+
+// IsIgnored reports whether a path of a template layer matches the layer's .jrxignore
+func (l TemplateLayer) IsIgnored(relPath string, isDir bool) bool {
+	return matchPatterns(l.Ignore, strings.Split(filepath.ToSlash(relPath), "/"), isDir)
+}
+
+// IsVerbatim reports whether the template file must be copied without rendering
+func (rt *RootTemplate) IsVerbatim(relPath string) bool {
+	return matchPatterns(rt.ProjectInfo.CopyVerbatim, strings.Split(filepath.ToSlash(relPath), "/"), false)
+}
+
+// matchPatterns matches a path against gitignore style patterns, "!" negations included
+func matchPatterns(patterns []string, segments []string, isDir bool) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	parsed := make([]gitignore.Pattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		parsed = append(parsed, gitignore.ParsePattern(pattern, nil))
+	}
+	return gitignore.NewMatcher(parsed).Match(segments, isDir)
+}
+
+// ParseIgnoreFile returns the patterns of a gitignore style file, without comments and blank lines
+func ParseIgnoreFile(content string) []string {
+	var patterns []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+// applies reports whether the rule lets its paths into the project
+func (r FileRule) applies(values map[string]any) bool {
+	if len(r.When) > 0 && !conditionsHold(r.When, values) {
+		return false
+	}
+	if len(r.Unless) > 0 && conditionsHold(r.Unless, values) {
+		return false
+	}
+	return true
+}
+
+// conditionsHold reports whether every variable has (one of) the expected values
+func conditionsHold(conditions map[string]any, values map[string]any) bool {
+	for key, expected := range conditions {
+		value := fmt.Sprint(values[key])
+		switch accepted := expected.(type) {
+		case []any:
+			if !slices.ContainsFunc(accepted, func(a any) bool { return fmt.Sprint(a) == value }) {
+				return false
+			}
+		default:
+			if fmt.Sprint(accepted) != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// String describes the rule conditions, e.g. "when docker = true"
+func (r FileRule) String() string {
+	var parts []string
+	for _, section := range []struct {
+		name       string
+		conditions map[string]any
+	}{{"when", r.When}, {"unless", r.Unless}} {
+		if len(section.conditions) == 0 {
+			continue
+		}
+		keys := make([]string, 0, len(section.conditions))
+		for key := range section.conditions {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			keys[i] = fmt.Sprintf("%s = %v", key, section.conditions[key])
+		}
+		parts = append(parts, section.name+" "+strings.Join(keys, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,89 @@
+package templates
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/navigator-systems/jrx/internal/config"
+	"github.com/navigator-systems/jrx/internal/errors"
+)
+
+// Sources holds a template manager for every configured template source
+type Sources struct {
+	managers map[string]*TemplateManager
+	names    []string
+}
+
+// NewSources creates the template managers of every template source in the JRX config.
+// Sources that can't be used are reported and left out.
+func NewSources(cfg config.JRXConfig) (*Sources, error) {
+	s := &Sources{managers: make(map[string]*TemplateManager)}
+
+	var errs []error
+	for _, name := range cfg.TemplateSourceNames() {
+		sourceCfg, err := cfg.SourceConfig(name)
+		if err == nil && name != "" && sourceCfg.TemplatesRepo == "" && sourceCfg.TemplatesDir == "" {
+			err = fmt.Errorf("template source '%s' has no repo or dir", name)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.managers[name] = NewTemplateManager(sourceCfg)
+		s.names = append(s.names, name)
+	}
+	return s, stderrors.Join(errs...)
+}
+
+// Get returns the template manager of a source, "" being the top-level templates_repo
+func (s *Sources) Get(name string) (*TemplateManager, error) {
+	tm, ok := s.managers[name]
+	if !ok {
+		if name == "" {
+			return nil, fmt.Errorf("no templates_repo is configured, address templates as <source>/<template> (sources: %s)", strings.Join(s.names, ", "))
+		}
+		return nil, fmt.Errorf("template source '%s' is not configured", name)
+	}
+	return tm, nil
+}
+
+// Names returns the source names, the top-level source "" first
+func (s *Sources) Names() []string {
+	return s.names
+}
+
+// Resolve returns the template manager and the template key of a template address.
+// Templates are addressed as <source>/<template>, or by key alone in the top-level source.
+func (s *Sources) Resolve(address string) (*TemplateManager, string, error) {
+	if source, name, ok := strings.Cut(address, "/"); ok {
+		if tm, known := s.managers[source]; known && source != "" {
+			return tm, name, nil
+		}
+	}
+	tm, err := s.Get("")
+	return tm, address, err
+}
+
+// Initialize downloads the templates of every source, reporting each one that fails
+func (s *Sources) Initialize() error {
+	var errs []error
+	for _, name := range s.names {
+		if err := s.managers[name].Initialize(); err != nil {
+			if name != "" {
+				err = errors.NewError(fmt.Sprintf("template source '%s'", name), err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// QualifiedName returns the address of a template: <source>/<key>, or the key alone
+// for the top-level source
+func QualifiedName(source, key string) string {
+	if source == "" {
+		return key
+	}
+	return source + "/" + key
+}
@@ -0,0 +1,179 @@
+package templates
+
+import (
+	stderrors "errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/navigator-systems/jrx/internal/errors"
+)
+
+// Variable types supported in vars.toml
+const (
+	VarTypeString = "string"
+	VarTypeInt    = "int"
+	VarTypeBool   = "bool"
+	VarTypeEnum   = "enum"
+)
+
+// VarType returns the type of the variable, string when none is declared
+func (v VariablesTemplate) VarType() string {
+	if v.Type == "" {
+		return VarTypeString
+	}
+	return v.Type
+}
+
+// checkDefinition reports mistakes in the declaration of a variable
+func (v VariablesTemplate) checkDefinition() error {
+	switch v.VarType() {
+	case VarTypeString, VarTypeInt, VarTypeBool:
+	case VarTypeEnum:
+		if len(v.Choices) == 0 {
+			return fmt.Errorf("variable '%s': enum without choices", v.Key)
+		}
+	default:
+		return fmt.Errorf("variable '%s': unknown type '%s'", v.Key, v.Type)
+	}
+	if v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			return fmt.Errorf("variable '%s': invalid pattern: %w", v.Key, err)
+		}
+	}
+	return nil
+}
+
+// Check validates a value against the declaration of the variable
+func (v VariablesTemplate) Check(value string) error {
+	if value == "" {
+		if v.Required {
+			return fmt.Errorf("%w '%s': a value is required", errors.ErrInvalidVariable, v.Key)
+		}
+		return nil
+	}
+
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w '%s': %s", errors.ErrInvalidVariable, v.Key, fmt.Sprintf(format, args...))
+	}
+
+	switch v.VarType() {
+	case VarTypeInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return invalid("'%s' is not an integer", value)
+		}
+		if v.Min != nil && n < *v.Min {
+			return invalid("%d is lower than the minimum %d", n, *v.Min)
+		}
+		if v.Max != nil && n > *v.Max {
+			return invalid("%d is greater than the maximum %d", n, *v.Max)
+		}
+	case VarTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return invalid("'%s' is not a boolean (true or false)", value)
+		}
+	default:
+		if v.Min != nil && len(value) < *v.Min {
+			return invalid("must be at least %d characters long", *v.Min)
+		}
+		if v.Max != nil && len(value) > *v.Max {
+			return invalid("must be at most %d characters long", *v.Max)
+		}
+	}
+
+	if len(v.Choices) > 0 && !slices.Contains(v.Choices, value) {
+		return invalid("'%s' is not one of %s", value, strings.Join(v.Choices, ", "))
+	}
+	if v.Pattern != "" {
+		re, err := regexp.Compile("^(?:" + v.Pattern + ")$")
+		if err != nil {
+			return invalid("invalid pattern: %v", err)
+		}
+		if !re.MatchString(value) {
+			return invalid("'%s' does not match %s", value, v.Pattern)
+		}
+	}
+	return nil
+}
+
+// TypedValue returns the current value converted to the variable type (int, bool or string)
+func (v VariablesTemplate) TypedValue() any {
+	switch v.VarType() {
+	case VarTypeInt:
+		if n, err := strconv.Atoi(v.Default); err == nil {
+			return n
+		}
+	case VarTypeBool:
+		if b, err := strconv.ParseBool(v.Default); err == nil {
+			return b
+		}
+		return false
+	}
+	return v.Default
+}
+
+// ApplyVariables sets user values on the template variables, computes the defaults left
+// and validates all of them. Keys that are not declared by the template are rejected.
+// It can be called again with more values, such as those set by pre hooks: computed defaults
+// that were not given explicitly are then evaluated again.
+// rt.ProjectName must be set, computed defaults may use it.
+func (rt *RootTemplate) ApplyVariables(values map[string]string) error {
+	var errs []error
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if !slices.ContainsFunc(rt.Variables, func(v VariablesTemplate) bool { return v.Key == key }) {
+			errs = append(errs, fmt.Errorf("%w '%s'", errors.ErrUnknownVariable, key))
+		}
+	}
+
+	if rt.explicit == nil {
+		rt.explicit = make(map[string]string, len(values))
+	}
+	maps.Copy(rt.explicit, values)
+	rt.SetVariables(values)
+
+	// Computed defaults need the final values of the variables they depend on
+	for i, variable := range rt.Variables {
+		if _, ok := rt.explicit[variable.Key]; !ok && variable.Expr != "" {
+			rt.Variables[i].Default = ""
+		}
+	}
+	if err := rt.ResolveComputed(rt.explicit); err != nil {
+		errs = append(errs, err)
+	} else if err := rt.ValidateVariables(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errors.NewError("apply variables", stderrors.Join(errs...))
+	}
+	return nil
+}
+
+// ValidateVariables checks the current value of every variable against its declaration
+func (rt *RootTemplate) ValidateVariables() error {
+	var errs []error
+	for _, variable := range rt.Variables {
+		if err := variable.Check(variable.Default); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// Values returns the variable values converted to their declared types, for use as {{ .Values.key }}
+func (rt *RootTemplate) Values() map[string]any {
+	values := make(map[string]any, len(rt.Variables))
+	for _, variable := range rt.Variables {
+		values[variable.Key] = variable.TypedValue()
+	}
+	return values
+}
@@ -1,18 +1,105 @@
 package templates
 
 import (
+	"fmt"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
-	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/navigator-systems/jrx/internal/errors"
 )
 
-// GetVersionsTags fetches tags from remote repository, filters by pattern, sorts by most recent, and limits results
+// LatestVersion is the version alias of the highest stable release tag
+const LatestVersion = "latest"
+
+// GetVersionsTags lists the tags of the templates mirror, or of the remote repository before the
+// first download, filters by pattern and version constraint, sorts by semantic version (most
+// recent first), and limits results. Pre-releases are left out unless templates_prerelease is set.
 func (tm *TemplateManager) GetVersionsTags() ([]string, error) {
-	publicKeys, err := ssh.NewPublicKeysFromFile("git", tm.config.SshKeyPath, tm.config.SshKeyPassphrase)
+	tags, ok := tm.mirrorTags()
+	if !ok {
+		var err error
+		if tags, err = tm.remoteTags(); err != nil {
+			return nil, err
+		}
+	}
+
+	var constraint *semver.Constraints
+	if tm.config.TemplatesVersionConstraint != "" {
+		var err error
+		if constraint, err = semver.NewConstraint(tm.config.TemplatesVersionConstraint); err != nil {
+			return nil, errors.NewError("parse templates_version_constraint", err)
+		}
+	}
+
+	type release struct {
+		tag     string
+		version *semver.Version
+	}
+	var releases []release
+	var otherTags []string
+	pattern := tm.config.TemplatesPatternGlob
+
+	// Filter tags
+	for _, tagName := range tags {
+		// Filter by pattern if specified
+		if pattern != "" {
+			matched, err := filepath.Match(pattern, tagName)
+			if err != nil {
+				return nil, errors.NewError("match pattern", err)
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		// Tags that are not semantic versions can't satisfy a constraint
+		version, err := semver.NewVersion(tagName)
+		if err != nil {
+			if constraint == nil {
+				otherTags = append(otherTags, tagName)
+			}
+			continue
+		}
+		if version.Prerelease() != "" && !tm.config.TemplatesPrerelease {
+			continue
+		}
+		if constraint != nil && !constraint.Check(version) {
+			continue
+		}
+		releases = append(releases, release{tag: tagName, version: version})
+	}
+
+	// Sort releases by semantic version, most recent first, then the other tags in reverse order
+	slices.SortFunc(releases, func(a, b release) int {
+		if c := b.version.Compare(a.version); c != 0 {
+			return c
+		}
+		return strings.Compare(b.tag, a.tag)
+	})
+	sort.Sort(sort.Reverse(sort.StringSlice(otherTags)))
+
+	tagVersions := make([]string, 0, len(releases)+len(otherTags))
+	for _, r := range releases {
+		tagVersions = append(tagVersions, r.tag)
+	}
+	tagVersions = append(tagVersions, otherTags...)
+
+	// Limit to max versions if specified
+	if tm.config.TemplatesMaxVersions > 0 && len(tagVersions) > tm.config.TemplatesMaxVersions {
+		tagVersions = tagVersions[:tm.config.TemplatesMaxVersions]
+	}
+
+	return tagVersions, nil
+}
+
+// remoteTags lists the tags of the remote templates repository
+func (tm *TemplateManager) remoteTags() ([]string, error) {
+	publicKeys, err := tm.auth()
 	if err != nil {
 		return nil, errors.NewError("create SSH keys", err)
 	}
@@ -27,44 +114,54 @@ func (tm *TemplateManager) GetVersionsTags() ([]string, error) {
 		return nil, errors.NewError("list remote references", err)
 	}
 
-	var tagVersions []string
-	pattern := tm.config.TemplatesPatternGlob
-
-	// Extract and filter tags
+	var tags []string
 	for _, ref := range refs {
 		if ref.Name().IsTag() {
-			tagName := ref.Name().Short()
-
-			// Filter by pattern if specified
-			if pattern != "" {
-				matched, err := filepath.Match(pattern, tagName)
-				if err != nil {
-					return nil, errors.NewError("match pattern", err)
-				}
-				if matched {
-					tagVersions = append(tagVersions, tagName)
-				}
-			} else {
-				tagVersions = append(tagVersions, tagName)
-			}
+			tags = append(tags, ref.Name().Short())
 		}
 	}
+	return tags, nil
+}
 
-	// Sort tags in descending order (most recent first)
-	// This is a simple lexicographic sort - for semantic versioning, consider using a semver library
-	sort.Sort(sort.Reverse(sort.StringSlice(tagVersions)))
+// ResolveRevision returns the commit SHA a template version in the cache was materialized from.
+// Local templates with uncommitted changes have no revision, an empty string is returned.
+func ResolveRevision(templatesDir, version string) (string, error) {
+	dir := VersionDir(templatesDir, version)
+	if commit := readRevision(dir); commit != "" {
+		return commit, nil
+	}
 
-	// Limit to max versions if specified
-	if tm.config.TemplatesMaxVersions > 0 && len(tagVersions) > tm.config.TemplatesMaxVersions {
-		tagVersions = tagVersions[:tm.config.TemplatesMaxVersions]
+	// Caches written before the mirror are git clones, local templates may be a working copy
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: version == LocalVersion})
+	if err != nil {
+		return "", errors.NewError("open template version", err)
 	}
 
-	return tagVersions, nil
+	head, err := repo.Head()
+	if err != nil {
+		return "", errors.NewError("resolve template revision", err)
+	}
+
+	// The commit did not produce what is rendered from a working copy with uncommitted changes
+	if version == LocalVersion {
+		dirty, err := hasLocalChanges(repo, dir)
+		if err != nil {
+			return "", errors.NewError("read template working copy status", err)
+		}
+		if dirty {
+			return "", nil
+		}
+	}
+
+	return head.Hash().String(), nil
 }
 
 // GetAvailableVersions returns a list of all available template versions
 // by combining branches and tags from config
 func (tm *TemplateManager) GetAvailableVersions() []string {
+	if tm.IsLocal() {
+		return []string{LocalVersion}
+	}
 	versions := make([]string, 0)
 
 	// Add all configured branches
@@ -79,11 +176,45 @@ func (tm *TemplateManager) GetAvailableVersions() []string {
 	return versions
 }
 
+// latestTag returns the highest stable release tag
+func (tm *TemplateManager) latestTag() (string, error) {
+	tags, err := tm.GetVersionsTags()
+	if err != nil {
+		return "", err
+	}
+	for _, tag := range tags {
+		if version, err := semver.NewVersion(tag); err == nil && version.Prerelease() == "" {
+			return tag, nil
+		}
+	}
+	return "", errors.NewError("resolve latest version", fmt.Errorf("no stable release tag is available"))
+}
+
+// ResolveVersion returns the cached version to use for a requested one: the default version
+// when empty, the highest stable release tag for latest, and always local for local templates
+func (tm *TemplateManager) ResolveVersion(version string) (string, error) {
+	// Local templates have a single version, whatever is asked for
+	if tm.IsLocal() {
+		return LocalVersion, nil
+	}
+	if version == "" {
+		version = tm.config.TemplatesDefault
+	}
+	if version == LatestVersion {
+		return tm.latestTag()
+	}
+	return version, nil
+}
+
 // ValidateVersion checks if a given version exists in the available versions
 func (tm *TemplateManager) ValidateVersion(version string) bool {
 	if version == "" {
 		return true // Empty version is valid (uses default)
 	}
+	if version == LatestVersion {
+		_, err := tm.latestTag()
+		return err == nil
+	}
 
 	availableVersions := tm.GetAvailableVersions()
 	for _, v := range availableVersions {
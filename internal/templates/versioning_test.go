@@ -0,0 +1,111 @@
+package templates
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/navigator-systems/jrx/internal/config"
+)
+
+// mirrorWithTags returns a cache directory whose mirror holds the given tags
+func mirrorWithTags(t *testing.T, tags ...string) string {
+	t.Helper()
+	cache := t.TempDir()
+	repo, err := git.PlainInit(filepath.Join(cache, mirrorDir), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range tags {
+		ref := plumbing.NewHashReference(plumbing.NewTagReferenceName(tag), plumbing.NewHash("1111111111111111111111111111111111111111"))
+		if err := repo.Storer.SetReference(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cache
+}
+
+func TestGetVersionsTags(t *testing.T) {
+	tags := []string{"v1.2.0", "1.10.0", "v1.9.0", "v2.0.0-rc.1", "nightly", "release-a", "1.2.0", "v0.9.1"}
+	tests := []struct {
+		name    string
+		cfg     config.JRXConfig
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "semantic order, other tags last",
+			want: []string{"1.10.0", "v1.9.0", "v1.2.0", "1.2.0", "v0.9.1", "release-a", "nightly"},
+		},
+		{
+			name: "prereleases",
+			cfg:  config.JRXConfig{TemplatesPrerelease: true},
+			want: []string{"v2.0.0-rc.1", "1.10.0", "v1.9.0", "v1.2.0", "1.2.0", "v0.9.1", "release-a", "nightly"},
+		},
+		{
+			name: "constraint leaves out other tags",
+			cfg:  config.JRXConfig{TemplatesVersionConstraint: ">= 1.2, < 1.10"},
+			want: []string{"v1.9.0", "v1.2.0", "1.2.0"},
+		},
+		{
+			name: "pattern",
+			cfg:  config.JRXConfig{TemplatesPatternGlob: "v*"},
+			want: []string{"v1.9.0", "v1.2.0", "v0.9.1"},
+		},
+		{
+			name: "max versions",
+			cfg:  config.JRXConfig{TemplatesMaxVersions: 2},
+			want: []string{"1.10.0", "v1.9.0"},
+		},
+		{
+			name:    "invalid constraint",
+			cfg:     config.JRXConfig{TemplatesVersionConstraint: "not a range"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.TemplatesCacheDir = mirrorWithTags(t, tags...)
+			got, err := NewTemplateManager(tt.cfg).GetVersionsTags()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetVersionsTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetVersionsTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatestTag(t *testing.T) {
+	tests := []struct {
+		name       string
+		tags       []string
+		prerelease bool
+		want       string
+		wantErr    bool
+	}{
+		{name: "highest release", tags: []string{"v1.2.0", "v1.10.0", "v1.9.3"}, want: "v1.10.0"},
+		{name: "prereleases are skipped", tags: []string{"v1.0.0", "v2.0.0-beta.1"}, prerelease: true, want: "v1.0.0"},
+		{name: "other tags are skipped", tags: []string{"nightly", "0.1.0"}, want: "0.1.0"},
+		{name: "no release", tags: []string{"nightly", "v3.0.0-rc.1"}, prerelease: true, wantErr: true},
+		{name: "no tags", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := NewTemplateManager(config.JRXConfig{
+				TemplatesCacheDir:   mirrorWithTags(t, tt.tags...),
+				TemplatesPrerelease: tt.prerelease,
+			})
+			got, err := tm.latestTag()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("latestTag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("latestTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package vars collects template variable values from the places users can give them:
+// vars files, the environment, command line flags and web forms.
+package vars
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/navigator-systems/jrx/internal/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of environment variables holding template variable values
+const EnvPrefix = "JRX_VAR_"
+
+// FormPrefix is the prefix of web form fields holding template variable values
+const FormPrefix = "var_"
+
+// Merge combines sets of values, later sets taking precedence over earlier ones
+func Merge(sets ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, set := range sets {
+		maps.Copy(merged, set)
+	}
+	return merged
+}
+
+// ParseAssignment parses a single key=value pair. The value is kept as is, commas,
+// equal signs and newlines included.
+func ParseAssignment(assignment string) (string, string, error) {
+	key, value, ok := strings.Cut(assignment, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", errors.NewError("parse variable", fmt.Errorf("'%s' is not in key=value format", assignment))
+	}
+	return key, value, nil
+}
+
+// ParseAssignments parses repeated key=value flags
+func ParseAssignments(assignments []string) (map[string]string, error) {
+	values := make(map[string]string, len(assignments))
+	for _, assignment := range assignments {
+		key, value, err := ParseAssignment(assignment)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// ParseList parses the legacy comma separated format key1=value1,key2=value2.
+// Surrounding quotes are removed from values.
+func ParseList(list string) (map[string]string, error) {
+	values := make(map[string]string)
+	if strings.TrimSpace(list) == "" {
+		return values, nil
+	}
+	for _, pair := range strings.Split(list, ",") {
+		key, value, err := ParseAssignment(strings.TrimSpace(pair))
+		if err != nil {
+			return nil, err
+		}
+		values[key] = strings.Trim(strings.TrimSpace(value), "\"'")
+	}
+	return values, nil
+}
+
+// LoadFile reads variable values from a TOML, YAML or JSON file, chosen by extension.
+// The file holds a flat table of variables; lists and tables are passed on as JSON.
+func LoadFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.NewError("read vars file", err)
+	}
+
+	raw := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(content, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".json":
+		err = json.Unmarshal(content, &raw)
+	default:
+		return nil, errors.NewError("read vars file", fmt.Errorf("unsupported format '%s', use .toml, .yaml, .yml or .json", filepath.Ext(path)))
+	}
+	if err != nil {
+		return nil, errors.NewError("decode vars file", err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if values[key], err = stringify(value); err != nil {
+			return nil, errors.NewError("decode vars file", fmt.Errorf("variable '%s': %w", key, err))
+		}
+	}
+	return values, nil
+}
+
+// stringify converts a decoded value to the string form used for template variables
+func stringify(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []any, map[string]any:
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// FromEnv returns the values of the given variables set as JRX_VAR_<key> in the environment.
+// The key is looked up as written first, then upper-cased.
+func FromEnv(keys []string, lookup func(string) (string, bool)) map[string]string {
+	values := make(map[string]string)
+	for _, key := range keys {
+		if value, ok := lookup(EnvPrefix + key); ok {
+			values[key] = value
+		} else if value, ok := lookup(EnvPrefix + strings.ToUpper(key)); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// FromForm returns the values of the var_<key> fields of a submitted form
+func FromForm(form url.Values) map[string]string {
+	values := make(map[string]string)
+	for field, submitted := range form {
+		if key, ok := strings.CutPrefix(field, FormPrefix); ok && len(submitted) > 0 {
+			values[key] = submitted[0]
+		}
+	}
+	return values
+}
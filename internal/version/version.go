@@ -0,0 +1,5 @@
+package version
+
+// Version is the jrx version, set at build time with
+// -ldflags "-X github.com/navigator-systems/jrx/internal/version.Version=v1.2.3"
+var Version = "dev"